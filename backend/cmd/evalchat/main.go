@@ -0,0 +1,71 @@
+// Command evalchat plays scripted conversations through the chat handler
+// against an in-memory store and prints a scorecard of how accurately the
+// trip fields were extracted and whether the trip reached the expected
+// stages, so changes to requirement merging and stage logic can be checked
+// before they are deployed.
+//
+//	go run ./cmd/evalchat cmd/evalchat/conversations
+//
+// The agent backend is chosen by AGENT_BACKEND as for the server, but
+// defaults to the local stub, whose parser only understands trip fields
+// written as a JSON object. Scripts in plain language need the deployed
+// agent or fixtures recorded from it. The command exits with status 1 if
+// any check fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/lda"
+	"github.com/yihao03/Aistronaut/m/v2/router"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: evalchat <script or directory>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	scripts, err := loadScripts(flag.Args())
+	if err != nil {
+		log.Fatal("Failed to load scripts:", err)
+	}
+
+	if os.Getenv("AGENT_BACKEND") == "" {
+		os.Setenv("AGENT_BACKEND", lda.BackendLocal)
+	}
+	if err := lda.Init(context.Background()); err != nil {
+		log.Fatal("Failed to initialize agent:", err)
+	}
+	if err := guardrail.Init(); err != nil {
+		log.Fatal("Failed to load guardrail rules:", err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	router.Setup(r)
+
+	var results []*Result
+	for _, script := range scripts {
+		result, err := runScript(r, script)
+		if err != nil {
+			log.Fatalf("Failed to run %s: %v", script.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	if !printScorecard(os.Stdout, results) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+)
+
+// Check is the outcome of comparing one expectation of a turn with the
+// stored trip. Field is "stage" for the stage.
+type Check struct {
+	Turn  int
+	Field string
+	Want  any
+	Got   any
+	OK    bool
+}
+
+// Result is how a script fared. Errors holds turns the handler rejected.
+type Result struct {
+	Name   string
+	Turns  int
+	Errors []string
+	Checks []Check
+}
+
+// runScript plays a script against a fresh store through the chat routes,
+// as a signed-in user, and checks the trip after every turn.
+func runScript(r *gin.Engine, script Script) (*Result, error) {
+	gdb, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	db.SetDB(gdb)
+	defer func() {
+		if sqlDB, err := gdb.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	for _, airport := range script.Airports {
+		if err := gdb.Create(&airport).Error; err != nil {
+			return nil, fmt.Errorf("failed to create airport: %v", err)
+		}
+	}
+	for _, flight := range script.Flights {
+		if err := gdb.Create(&flight).Error; err != nil {
+			return nil, fmt.Errorf("failed to create flight: %v", err)
+		}
+	}
+	for _, accommodation := range script.Accommodations {
+		if err := gdb.Create(&accommodation).Error; err != nil {
+			return nil, fmt.Errorf("failed to create accommodation: %v", err)
+		}
+	}
+
+	user := models.Users{
+		UserID:      uuid.New().String(),
+		Username:    "evalchat",
+		Email:       "evalchat@example.com",
+		Nationality: script.Nationality,
+	}
+	if err := gdb.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+	token, err := myjwt.GenerateJWTToken(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	var created struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	code, body := post(r, "/chat/create", token, nil)
+	if code != http.StatusOK || json.Unmarshal(body, &created) != nil {
+		return nil, fmt.Errorf("failed to create conversation: %d %s", code, body)
+	}
+
+	result := &Result{Name: script.Name, Turns: len(script.Turns)}
+	for i, turn := range script.Turns {
+		params := turn.CreateParams
+		params.ChatHistoryID = created.ConversationID
+		params.UserID = user.UserID
+		if code, body := post(r, "/chat/", token, params); code != http.StatusOK {
+			result.Errors = append(result.Errors, fmt.Sprintf("turn %d: %d %s", i+1, code, body))
+		}
+
+		var trip models.Trip
+		if err := gdb.Find(&trip, "trip_id = ?", created.ConversationID).Error; err != nil {
+			return nil, fmt.Errorf("failed to find trip: %v", err)
+		}
+		result.Checks = append(result.Checks, checkTurn(i+1, turn.Expect, trip)...)
+	}
+	return result, nil
+}
+
+func post(r *gin.Engine, path, token string, body any) (int, []byte) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// checkTurn compares the expectations of a turn with the stored trip.
+func checkTurn(turn int, expect Expect, trip models.Trip) []Check {
+	var checks []Check
+	if expect.Stage != "" {
+		checks = append(checks, Check{
+			Turn:  turn,
+			Field: "stage",
+			Want:  expect.Stage,
+			Got:   trip.Stage,
+			OK:    trip.Stage == expect.Stage,
+		})
+	}
+
+	// Compare in JSON, the shape the expectations are written in.
+	var stored map[string]any
+	data, _ := json.Marshal(trip)
+	json.Unmarshal(data, &stored)
+
+	fields := make([]string, 0, len(expect.Trip))
+	for field := range expect.Trip {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		want, got := expect.Trip[field], stored[field]
+		checks = append(checks, Check{Turn: turn, Field: field, Want: want, Got: got, OK: matches(want, got)})
+	}
+	return checks
+}
+
+// matches reports whether a stored trip field holds the expected value.
+// Strings match regardless of case and surrounding space, a date matches
+// any time on that day and a list matches the comma separated form lists
+// are stored in, since the agents are free in how they phrase all three.
+func matches(want, got any) bool {
+	switch want := want.(type) {
+	case string:
+		got, ok := got.(string)
+		if !ok {
+			return false
+		}
+		want, got = strings.TrimSpace(want), strings.TrimSpace(got)
+		if strings.EqualFold(want, got) {
+			return true
+		}
+		day, err := time.Parse("2006-01-02", want)
+		if err != nil {
+			return false
+		}
+		stored, err := time.Parse(time.RFC3339, got)
+		return err == nil && stored.Format("2006-01-02") == day.Format("2006-01-02")
+	case []any:
+		stored, ok := got.(string)
+		if !ok {
+			return false
+		}
+		items := strings.Split(stored, ",")
+		if stored == "" {
+			items = nil
+		}
+		if len(items) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !matches(want[i], items[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
+
+// printScorecard writes the accuracy of every script, then of every field
+// across them, then each failed check and rejected turn. It returns
+// whether everything passed.
+func printScorecard(w io.Writer, results []*Result) bool {
+	type tally struct{ ok, total int }
+	var fields, stages tally
+	perField := map[string]*tally{}
+	failed := 0
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONVERSATION\tTURNS\tERRORS\tFIELDS\tSTAGES")
+	for _, result := range results {
+		var f, s tally
+		for _, check := range result.Checks {
+			t := &f
+			if check.Field == "stage" {
+				t = &s
+			} else {
+				if perField[check.Field] == nil {
+					perField[check.Field] = &tally{}
+				}
+				perField[check.Field].total++
+				if check.OK {
+					perField[check.Field].ok++
+				}
+			}
+			t.total++
+			if check.OK {
+				t.ok++
+			}
+		}
+		fields.ok, fields.total = fields.ok+f.ok, fields.total+f.total
+		stages.ok, stages.total = stages.ok+s.ok, stages.total+s.total
+		failed += len(result.Errors)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d/%d\t%d/%d\n", result.Name, result.Turns, len(result.Errors), f.ok, f.total, s.ok, s.total)
+	}
+	tw.Flush()
+
+	if len(perField) > 0 {
+		names := make([]string, 0, len(perField))
+		for name := range perField {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(tw, "FIELD\tCORRECT")
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%d/%d\n", name, perField[name].ok, perField[name].total)
+		}
+		tw.Flush()
+	}
+
+	passed := failed == 0 && fields.ok == fields.total && stages.ok == stages.total
+	if !passed {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "FAILURES")
+		for _, result := range results {
+			for _, err := range result.Errors {
+				fmt.Fprintf(w, "%s %s\n", result.Name, err)
+			}
+			for _, check := range result.Checks {
+				if !check.OK {
+					fmt.Fprintf(w, "%s turn %d: %s: want %v, got %v\n", result.Name, check.Turn, check.Field, format(check.Want), format(check.Got))
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nfields %s, stages %s, %d rejected turns\n", percent(fields.ok, fields.total), percent(stages.ok, stages.total), failed)
+	return passed
+}
+
+func format(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func percent(ok, total int) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%% (%d/%d)", 100*float64(ok)/float64(total), ok, total)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+)
+
+// Script is a scripted conversation: the catalog it runs against, the turns
+// the user sends and what the trip should look like after each of them.
+type Script struct {
+	Name           string                  `json:"name"`
+	Nationality    string                  `json:"nationality"`
+	Airports       []models.Airports       `json:"airports"`
+	Flights        []models.Flights        `json:"flights"`
+	Accommodations []models.Accommodations `json:"accommodations"`
+	Turns          []Turn                  `json:"turns"`
+}
+
+// Turn is one user message, sent as the body of POST /chat/ with the
+// conversation filled in, and what it should lead to.
+type Turn struct {
+	chatparams.CreateParams
+	Expect Expect `json:"expect"`
+}
+
+// Expect holds the stage the trip should be in after a turn and the trip
+// fields, by their JSON names, it should have by then. Either may be left
+// out to check nothing.
+type Expect struct {
+	Stage string         `json:"stage"`
+	Trip  map[string]any `json:"trip"`
+}
+
+// loadScripts reads the scripts in the given files and in the .json files
+// of the given directories, in path order.
+func loadScripts(paths []string) ([]Script, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	var scripts []Script
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var script Script
+		if err := json.Unmarshal(data, &script); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+		}
+		if script.Name == "" {
+			script.Name = filepath.Base(file)
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tables are the models the chat handlers read and write. Each is created
+// with the model's primary key, which matches its DynamoDB key, so writes
+// that would collide there collide here too.
+var tables = []any{
+	&models.Users{},
+	&models.UserPreferences{},
+	&models.Trip{},
+	&models.ChatHistory{},
+	&models.Flights{},
+	&models.Airports{},
+	&models.FlightBookings{},
+	&models.Accommodations{},
+	&models.AccommodationBookings{},
+	&models.ConversationSummary{},
+	&models.TripChange{},
+	&models.Itinerary{},
+	&models.IdempotencyRecord{},
+	&models.AgentTrace{},
+	&models.MessageFeedback{},
+	&models.AgentUsage{},
+	&models.DailyUsage{},
+}
+
+// openStore opens an empty in-memory database with every table, so each
+// script starts from nothing and leaves nothing behind.
+func openStore() (*gorm.DB, error) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", uuid.New().String())
+	gdb, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %v", err)
+	}
+	if err := gdb.AutoMigrate(tables...); err != nil {
+		return nil, fmt.Errorf("failed to create tables: %v", err)
+	}
+	return gdb, nil
+}
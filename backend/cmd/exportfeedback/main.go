@@ -0,0 +1,47 @@
+// Command exportfeedback writes the rated agent messages as JSON lines, each
+// with the user message it answered and the agent requests behind it.
+//
+//	go run ./cmd/exportfeedback -rating down -since 2025-01-01 > feedback.jsonl
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/chat"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+func main() {
+	rating := flag.String("rating", "", "only export messages rated up or down")
+	since := flag.String("since", "", "only export ratings made on or after this date (YYYY-MM-DD)")
+	flag.Parse()
+
+	if *rating != "" && *rating != models.RatingUp && *rating != models.RatingDown {
+		log.Fatalf("rating must be %q or %q", models.RatingUp, models.RatingDown)
+	}
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = time.Parse("2006-01-02", *since); err != nil {
+			log.Fatal("Invalid since date:", err)
+		}
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found or could not be loaded")
+	}
+	if err := db.Setup(); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	count, err := chat.ExportFeedback(os.Stdout, *rating, from)
+	if err != nil {
+		log.Fatal("Failed to export feedback:", err)
+	}
+	log.Printf("Exported %d rated messages", count)
+}
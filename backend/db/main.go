@@ -26,6 +26,11 @@ func Setup() error {
 	return err
 }
 
+// SetDB replaces the database, e.g. with an in-memory one in tools.
+func SetDB(gdb *gorm.DB) {
+	db = gdb
+}
+
 func GetDB() *gorm.DB {
 	if db == nil {
 		log.Panic("Database not initialized. Call Setup first.")
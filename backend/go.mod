@@ -7,6 +7,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/lambda v1.77.4
 	github.com/gin-contrib/cors v1.7.6
 	github.com/gin-gonic/gin v1.10.1
+	github.com/glebarez/sqlite v1.11.0
 	github.com/joho/godotenv v1.5.1
 	gorm.io/gorm v1.31.0
 )
@@ -29,9 +30,16 @@ require (
 	github.com/aws/smithy-go v1.23.0 // indirect
 	github.com/btnguyen2k/consu/g18 v0.1.0 // indirect
 	github.com/btnguyen2k/consu/reddo v0.1.9 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/glebarez/go-sqlite v1.21.2 // indirect
 	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/miyamo2/godynamo v1.4.0 // indirect
 	github.com/miyamo2/sqldav v0.2.1 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	modernc.org/libc v1.22.5 // indirect
+	modernc.org/mathutil v1.5.0 // indirect
+	modernc.org/memory v1.5.0 // indirect
+	modernc.org/sqlite v1.23.1 // indirect
 )
 
 require (
@@ -0,0 +1,188 @@
+package guardrail
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Actions the guardrails take on a user message, from least to most severe.
+// A sanitized message goes on with the offending text replaced; a blocked
+// one is answered with a canned reply and never reaches the agents.
+const (
+	ActionPass     = "pass"
+	ActionSanitize = "sanitize"
+	ActionBlock    = "block"
+)
+
+var severity = map[string]int{ActionPass: 0, ActionSanitize: 1, ActionBlock: 2}
+
+// Names of the built-in checks, as reported in Decision.Rules.
+const (
+	RuleMaxLength      = "max_length"
+	RuleEmail          = "email"
+	RuleCardNumber     = "card_number"
+	RulePassportNumber = "passport_number"
+	RulePhoneNumber    = "phone_number"
+)
+
+// defaultMaxLength applies when GUARDRAIL_MAX_LENGTH is unset.
+const defaultMaxLength = 2000
+
+// removed replaces the text matched by a sanitize rule.
+const removed = "[removed]"
+
+// Rule is a prompt-injection pattern and the action taken on a message
+// matching it, ActionSanitize or ActionBlock.
+type Rule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+
+	re *regexp.Regexp
+}
+
+// DefaultRules catch the common attempts to override the agents'
+// instructions. Role markers are only stripped, since pasted chat logs
+// contain them innocently.
+var DefaultRules = []Rule{
+	{
+		Name:    "ignore_instructions",
+		Pattern: `(?i)\b(ignore|disregard|forget|override)\b[^.\n]{0,40}\b(previous|prior|above|earlier|system|your)\b[^.\n]{0,20}\b(instructions?|prompts?|rules|directions|guidelines)\b`,
+		Action:  ActionBlock,
+	},
+	{
+		Name:    "reveal_prompt",
+		Pattern: `(?i)\b(reveal|show|print|repeat|output|tell me)\b[^.\n]{0,40}\b(system|hidden|initial|original)\s+(prompt|instructions?|message)`,
+		Action:  ActionBlock,
+	},
+	{
+		Name:    "role_override",
+		Pattern: `(?i)\b(you are now|from now on you are|pretend (to be|you are)|act as)\b[^.\n]{0,40}\b(unrestricted|unfiltered|jailbroken|DAN|developer mode|no (rules|restrictions|limits))\b`,
+		Action:  ActionBlock,
+	},
+	{
+		Name:    "role_markers",
+		Pattern: `(?im)^\s*(system|assistant|developer)\s*:|<\|?(system|assistant|im_start|im_end)\|?>|\[/?(INST|SYS)\]`,
+		Action:  ActionSanitize,
+	},
+}
+
+var rules = mustCompile(DefaultRules)
+
+// Init loads the prompt-injection rules from the JSON file named by
+// GUARDRAIL_RULES, a list of Rule that replaces DefaultRules. Without it the
+// defaults apply.
+func Init() error {
+	path := os.Getenv("GUARDRAIL_RULES")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read guardrail rules: %v", err)
+	}
+	var loaded []Rule
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to parse guardrail rules: %v", err)
+	}
+	compiled, err := compile(loaded)
+	if err != nil {
+		return err
+	}
+
+	rules = compiled
+	return nil
+}
+
+func compile(list []Rule) ([]Rule, error) {
+	compiled := make([]Rule, 0, len(list))
+	for _, rule := range list {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("guardrail rule %q has no name", rule.Pattern)
+		}
+		if rule.Action != ActionSanitize && rule.Action != ActionBlock {
+			return nil, fmt.Errorf("guardrail rule %s: action must be %s or %s, got: %q", rule.Name, ActionSanitize, ActionBlock, rule.Action)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("guardrail rule %s: %v", rule.Name, err)
+		}
+		rule.re = re
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+func mustCompile(list []Rule) []Rule {
+	compiled, err := compile(list)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// MaxLength returns the longest message, in characters, that is passed on,
+// set with GUARDRAIL_MAX_LENGTH.
+func MaxLength() int {
+	if length, err := strconv.Atoi(os.Getenv("GUARDRAIL_MAX_LENGTH")); err == nil && length > 0 {
+		return length
+	}
+	return defaultMaxLength
+}
+
+// Decision is what the guardrails made of a user message.
+type Decision struct {
+	Action string
+	// Text is the message to store and send on, with personal data and
+	// sanitized patterns replaced. A message over the length cap is cut to
+	// it.
+	Text string
+	// Rules names the checks and rules that matched, in the order they ran.
+	Rules []string
+}
+
+// Check runs the guardrails over a user message: the length cap, then the
+// personal data detectors, which redact what they find, then the
+// prompt-injection rules.
+func Check(text string) Decision {
+	decision := Decision{Action: ActionPass, Text: text}
+
+	if limit := MaxLength(); utf8.RuneCountInString(text) > limit {
+		decision.Text = string([]rune(text)[:limit])
+		decision.flag(RuleMaxLength, ActionBlock)
+	}
+
+	for _, detector := range piiDetectors {
+		var found bool
+		if decision.Text, found = detector.redact(decision.Text); found {
+			decision.flag(detector.rule, ActionSanitize)
+		}
+	}
+
+	for _, rule := range rules {
+		if !rule.re.MatchString(decision.Text) {
+			continue
+		}
+		decision.flag(rule.Name, rule.Action)
+		if rule.Action == ActionSanitize {
+			decision.Text = rule.re.ReplaceAllString(decision.Text, removed)
+		}
+	}
+
+	return decision
+}
+
+func (d *Decision) flag(rule, action string) {
+	if !slices.Contains(d.Rules, rule) {
+		d.Rules = append(d.Rules, rule)
+	}
+	if severity[action] > severity[d.Action] {
+		d.Action = action
+	}
+}
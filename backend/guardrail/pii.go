@@ -0,0 +1,131 @@
+package guardrail
+
+import (
+	"regexp"
+	"strings"
+)
+
+// piiDetector finds one kind of personal data and replaces it with a
+// placeholder. If the pattern has a group, only the group is replaced.
+type piiDetector struct {
+	rule        string
+	pattern     *regexp.Regexp
+	placeholder string
+	// valid, if set, rejects text that only looks like the data, given the
+	// whole message and where the match starts and ends.
+	valid func(text string, start, end int) bool
+}
+
+// piiDetectors run in order, so card numbers are redacted before they can
+// be taken for phone numbers.
+var piiDetectors = []piiDetector{
+	{
+		rule:        RuleEmail,
+		pattern:     regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		placeholder: "[email]",
+	},
+	{
+		rule:        RuleCardNumber,
+		pattern:     regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		placeholder: "[card number]",
+		valid:       isCardNumber,
+	},
+	{
+		rule:        RulePassportNumber,
+		pattern:     regexp.MustCompile(`(?i)\bpassport(?:\s+(?:no|num|number))?\.?\s*(?:is|:|#)?\s*([A-Z0-9]{6,9})\b`),
+		placeholder: "[passport number]",
+		valid:       hasDigit,
+	},
+	{
+		// Passport numbers written on their own, e.g. E1234567N.
+		rule:        RulePassportNumber,
+		pattern:     regexp.MustCompile(`\b[A-Z]{1,2}[0-9]{6,8}[A-Z]?\b`),
+		placeholder: "[passport number]",
+	},
+	{
+		rule:        RulePhoneNumber,
+		pattern:     regexp.MustCompile(`[+(]?\d[\d\s().-]{5,}\d`),
+		placeholder: "[phone number]",
+		valid:       isPhoneNumber,
+	},
+}
+
+func (p piiDetector) redact(text string) (string, bool) {
+	var b strings.Builder
+	last := 0
+	found := false
+	for _, match := range p.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		if p.valid != nil && !p.valid(text, start, end) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(p.placeholder)
+		last = end
+		found = true
+	}
+	if !found {
+		return text, false
+	}
+	b.WriteString(text[last:])
+	return b.String(), true
+}
+
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func hasDigit(text string, start, end int) bool {
+	return digitsOf(text[start:end]) != ""
+}
+
+// isCardNumber accepts 13 to 19 digits passing the Luhn check.
+func isCardNumber(text string, start, end int) bool {
+	digits := digitsOf(text[start:end])
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+var (
+	datePattern  = regexp.MustCompile(`^(\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4})$`)
+	phoneShape   = regexp.MustCompile(`^(\+\d|(\(\d{2,4}\)\s?|\d{2,4}[-.])\d{3,4}[-.]\d{3,4}$)`)
+	phoneContext = regexp.MustCompile(`(?i)\b(phone|mobile|cell|tel|call|whatsapp|contact)\b\W*$`)
+)
+
+// isPhoneNumber accepts 7 to 15 digits that are not a date and are shaped
+// like a phone number: with an international prefix, as in +65 9123 4567, or
+// in groups such as (555) 123-4567 or 555-123-4567. Other runs of digits only
+// count after a word like "phone", so budgets and ranges such as 3000-4000 or
+// 1000000 - 2000000 IDR are left alone.
+func isPhoneNumber(text string, start, end int) bool {
+	match := strings.TrimSpace(text[start:end])
+	digits := digitsOf(match)
+	if len(digits) < 7 || len(digits) > 15 || datePattern.MatchString(match) {
+		return false
+	}
+	if phoneShape.MatchString(match) {
+		return true
+	}
+	return phoneContext.MatchString(text[max(0, start-24):start])
+}
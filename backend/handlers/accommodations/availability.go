@@ -0,0 +1,105 @@
+package accommodations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// FindAvailable returns the accommodations in a trip's destination cities
+// with enough rooms free over the trip's dates to sleep every traveller. An
+// accommodation that does not record its rooms or occupancy is not filtered
+// on them.
+func FindAvailable(trip *models.Trip) ([]models.Accommodations, error) {
+	checkIn, checkOut, err := StayDates(trip)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := GetAccommodationsByCities(trip.DestinationCities)
+	if err != nil {
+		return nil, err
+	}
+
+	var counted []string
+	for _, accommodation := range candidates {
+		if accommodation.TotalRooms > 0 {
+			counted = append(counted, accommodation.AccommodationID)
+		}
+	}
+	booked := map[string]int{}
+	if len(counted) > 0 {
+		db := db.GetDB()
+		var bookings []models.AccommodationBookings
+		if err := db.Where("accommodation_id IN ?", counted).Find(&bookings).Error; err != nil {
+			return nil, fmt.Errorf("failed to retrieve accommodation bookings: %v", err)
+		}
+		for _, booking := range bookings {
+			if booking.Invalidated || !overlaps(booking, checkIn, checkOut) {
+				continue
+			}
+			booked[booking.AccommodationID] += max(booking.NumberOfRooms, 1)
+		}
+	}
+
+	var available []models.Accommodations
+	for _, accommodation := range candidates {
+		if accommodation.TotalRooms > 0 &&
+			accommodation.TotalRooms-booked[accommodation.AccommodationID] < RoomsNeeded(accommodation, trip) {
+			continue
+		}
+		available = append(available, accommodation)
+	}
+
+	return available, nil
+}
+
+// RoomsNeeded is the number of rooms a trip's travellers take at an
+// accommodation, one if its occupancy is unknown.
+func RoomsNeeded(accommodation models.Accommodations, trip *models.Trip) int {
+	if accommodation.MaxGuestsPerRoom <= 0 {
+		return 1
+	}
+	travelers := max(trip.NumberOfTravelers, 1)
+	return (travelers + accommodation.MaxGuestsPerRoom - 1) / accommodation.MaxGuestsPerRoom
+}
+
+// StayDates returns the check-in and check-out days of a trip, its start and
+// end dates.
+func StayDates(trip *models.Trip) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, trip.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date format: %v", err)
+	}
+	end, err := time.Parse("2006-01-02", trip.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date format: %v", err)
+	}
+	return day(start), end, nil
+}
+
+// overlaps reports whether a booking holds its rooms on any night between
+// checkIn and checkOut. Bookings without dates are taken to overlap.
+func overlaps(booking models.AccommodationBookings, checkIn, checkOut time.Time) bool {
+	bookedIn, errIn := parseDay(booking.CheckInDate)
+	bookedOut, errOut := parseDay(booking.CheckOutDate)
+	if errIn != nil || errOut != nil {
+		return true
+	}
+	return bookedIn.Before(checkOut) && checkIn.Before(bookedOut)
+}
+
+// parseDay reads the day of a booking date, stored either as a date or as
+// an RFC 3339 time.
+func parseDay(value string) (time.Time, error) {
+	if len(value) < len("2006-01-02") {
+		return time.Time{}, fmt.Errorf("invalid date format: %q", value)
+	}
+	return time.Parse("2006-01-02", value[:len("2006-01-02")])
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
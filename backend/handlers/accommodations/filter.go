@@ -0,0 +1,74 @@
+package accommodations
+
+import (
+	"fmt"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/accommodationsparams"
+)
+
+// GetAccommodationsByCities retrieves accommodations located in any of the given cities
+func GetAccommodationsByCities(cities []string) ([]models.Accommodations, error) {
+	if len(cities) == 0 {
+		return nil, nil
+	}
+
+	db := db.GetDB()
+	var accommodations []models.Accommodations
+
+	if err := db.Where("city IN ?", cities).Find(&accommodations).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve accommodations: %v", err)
+	}
+
+	return accommodations, nil
+}
+
+// FindAccommodations runs an accommodation search: accommodations matching
+// the given destination, type and guest count, best rated first.
+func FindAccommodations(params accommodationsparams.SearchParams) ([]models.Accommodations, error) {
+	db := db.GetDB()
+	var accommodations []models.Accommodations
+
+	// Build search query
+	query := db
+
+	if params.Destination != nil {
+		// DynamoDB-compatible approach: use exact match first
+		destination := *params.Destination
+		query = query.Where("city = ? OR country = ?", destination, destination)
+	}
+
+	if params.Type != nil {
+		query = query.Where("type = ?", *params.Type)
+	}
+
+	// Filter by availability (basic check - in real app you'd check bookings table)
+	if params.Guests != nil {
+		if guestCount, err := params.GetGuestsInt(); err == nil && guestCount > 0 {
+			// Assuming accommodations have a max_guests field or similar logic
+			query = query.Where("max_guests >= ? OR max_guests IS NULL", guestCount)
+		}
+	}
+
+	// Order by star rating (highest first)
+	query = query.Order("star_rating DESC")
+
+	if err := query.Find(&accommodations).Error; err != nil {
+		return nil, fmt.Errorf("failed to search accommodations: %v", err)
+	}
+
+	return accommodations, nil
+}
+
+// FindAccommodationByID retrieves a single accommodation.
+func FindAccommodationByID(accommodationID string) (*models.Accommodations, error) {
+	db := db.GetDB()
+
+	var accommodation models.Accommodations
+	if err := db.Where("accommodation_id = ?", accommodationID).First(&accommodation).Error; err != nil {
+		return nil, fmt.Errorf("failed to find accommodation %s: %v", accommodationID, err)
+	}
+
+	return &accommodation, nil
+}
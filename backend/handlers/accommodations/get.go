@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/accommodationsparams"
 )
@@ -18,7 +19,7 @@ func GetAllAccommodations(c *gin.Context) {
 	var params accommodationsparams.SearchParams
 	if err := c.ShouldBindQuery(&params); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid query parameters",
+			"error":   i18n.T(c, "error.invalid_query_parameters"),
 			"details": err.Error(),
 		})
 		return
@@ -27,7 +28,7 @@ func GetAllAccommodations(c *gin.Context) {
 	// Validate parameters
 	if err := params.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Validation failed",
+			"error":   i18n.T(c, "error.validation_failed"),
 			"details": err.Error(),
 		})
 		return
@@ -82,7 +83,7 @@ func GetAllAccommodations(c *gin.Context) {
 	// Execute query
 	if err := query.Find(&accommodations).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to retrieve accommodations",
+			"error":   i18n.T(c, "error.failed_to_retrieve_accommodations"),
 			"details": err.Error(),
 		})
 		return
@@ -92,21 +93,19 @@ func GetAllAccommodations(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"accommodations": accommodations,
 		"count":          len(accommodations),
-		"message":        "Accommodations retrieved successfully",
+		"message":        i18n.T(c, "message.accommodations_retrieved"),
 	})
 }
 
 // GetAccommodationByID retrieves a specific accommodation by ID
 func GetAccommodationByID(c *gin.Context) {
-	db := db.GetDB()
 	accommodationID := c.Param("id")
 
-	var accommodation models.Accommodations
-
 	// Find accommodation by ID
-	if err := db.Where("accommodation_id = ?", accommodationID).First(&accommodation).Error; err != nil {
+	accommodation, err := FindAccommodationByID(accommodationID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
-			"error":            "Accommodation not found",
+			"error":            i18n.T(c, "error.accommodation_not_found"),
 			"accommodation_id": accommodationID,
 		})
 		return
@@ -114,20 +113,17 @@ func GetAccommodationByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"accommodation": accommodation,
-		"message":       "Accommodation retrieved successfully",
+		"message":       i18n.T(c, "message.accommodation_retrieved"),
 	})
 }
 
 // SearchAccommodations provides advanced search functionality
 func SearchAccommodations(c *gin.Context) {
-	db := db.GetDB()
-	var accommodations []models.Accommodations
-
 	// Bind search parameters to struct
 	var params accommodationsparams.SearchParams
 	if err := c.ShouldBindQuery(&params); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid search parameters",
+			"error":   i18n.T(c, "error.invalid_search_parameters"),
 			"details": err.Error(),
 		})
 		return
@@ -136,39 +132,16 @@ func SearchAccommodations(c *gin.Context) {
 	// Validate parameters
 	if err := params.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Validation failed",
+			"error":   i18n.T(c, "error.validation_failed"),
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Build search query
-	query := db
-
-	if params.Destination != nil {
-		// DynamoDB-compatible approach: use exact match first
-		destination := *params.Destination
-		query = query.Where("city = ? OR country = ?", destination, destination)
-	}
-
-	if params.Type != nil {
-		query = query.Where("type = ?", *params.Type)
-	}
-
-	// Filter by availability (basic check - in real app you'd check bookings table)
-	if params.Guests != nil {
-		if guestCount, err := params.GetGuestsInt(); err == nil && guestCount > 0 {
-			// Assuming accommodations have a max_guests field or similar logic
-			query = query.Where("max_guests >= ? OR max_guests IS NULL", guestCount)
-		}
-	}
-
-	// Order by star rating (highest first)
-	query = query.Order("star_rating DESC")
-
-	if err := query.Find(&accommodations).Error; err != nil {
+	accommodations, err := FindAccommodations(params)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to search accommodations",
+			"error":   i18n.T(c, "error.failed_to_search_accommodations"),
 			"details": err.Error(),
 		})
 		return
@@ -178,7 +151,7 @@ func SearchAccommodations(c *gin.Context) {
 		"accommodations": accommodations,
 		"count":          len(accommodations),
 		"search_params":  params,
-		"message":        "Accommodation search completed successfully",
+		"message":        i18n.T(c, "message.accommodation_search_completed"),
 	})
 }
 
@@ -191,7 +164,7 @@ func GetAccommodationsByCity(c *gin.Context) {
 
 	if err := db.Where("city = ?", city).Find(&accommodations).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to retrieve accommodations for city",
+			"error":   i18n.T(c, "error.failed_to_retrieve_accommodations_for_city"),
 			"details": err.Error(),
 		})
 		return
@@ -201,6 +174,6 @@ func GetAccommodationsByCity(c *gin.Context) {
 		"accommodations": accommodations,
 		"city":           city,
 		"count":          len(accommodations),
-		"message":        "Accommodations retrieved successfully for " + city,
+		"message":        i18n.T(c, "message.accommodations_retrieved_for_city", city),
 	})
 }
@@ -0,0 +1,278 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// answerTurn applies the structured answer of the last message of
+// chatHistories to the trip or its bookings and appends the outcome to the
+// conversation, without asking the agent.
+func answerTurn(c *gin.Context,
+	trip *models.Trip,
+	body chatparams.CreateParams,
+	chatHistories []models.ChatHistory,
+) (*chatview.ChatResponse, *chatError) {
+	if _, err := resolveStage(trip); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_resolve_stage"), err)
+	}
+
+	var resMsg *models.ChatHistory
+	var pending []models.TripChange
+	var chatErr *chatError
+	switch body.ContentType {
+	case models.ContentOptionSelection:
+		resMsg, chatErr = answerOption(c, trip, body.Answer, chatHistories)
+	case models.ContentDateRange:
+		// Trips keep their start as RFC3339 and their end as a plain date.
+		start, _ := time.Parse("2006-01-02", body.Answer.StartDate)
+		resMsg, pending, chatErr = answerRequirements(c, trip, models.Trip{
+			StartDate: start.Format(time.RFC3339),
+			EndDate:   body.Answer.EndDate,
+		}, nil)
+	case models.ContentTravelerCount:
+		resMsg, pending, chatErr = answerRequirements(c, trip, models.Trip{
+			NumberOfTravelers: body.Answer.Adults + body.Answer.Children + body.Answer.Infants,
+			AdultsCount:       body.Answer.Adults,
+			ChildrenCount:     body.Answer.Children,
+			InfantsCount:      body.Answer.Infants,
+		}, map[string]bool{"ChildrenCount": true, "InfantsCount": true})
+	case models.ContentConfirmation:
+		resMsg, chatErr = answerConfirmation(c, trip, *body.Answer.Confirmed)
+	default:
+		return nil, newChatError(400, i18n.T(c, "error.validation_failed"), fmt.Errorf("unknown content_type: %d", body.ContentType))
+	}
+	if chatErr != nil {
+		return nil, chatErr
+	}
+
+	return &chatview.ChatResponse{
+		ConversationID:      trip.TripID,
+		Content:             resMsg.Message,
+		Object:              resMsg.ReqObject,
+		FlightObject:        resMsg.FlightObject,
+		AccommodationObject: resMsg.AccommodationObject,
+		PendingChanges:      pending,
+		Stage:               trip.Stage,
+		CreatedAt:           resMsg.Timestamp.ToString(),
+		IsUser:              false,
+	}, nil
+}
+
+// answerOption books the flight or accommodation the user picked. Picking a
+// mode books the flights chosen for that mode in the latest trip plans.
+func answerOption(c *gin.Context,
+	trip *models.Trip,
+	answer *chatparams.Answer,
+	chatHistories []models.ChatHistory,
+) (*models.ChatHistory, *chatError) {
+	db := db.GetDB()
+
+	if answer.Option == chatparams.OptionAccommodation {
+		if err := models.ValidateStageTransition(trip.Stage, models.StageReviewing); err != nil {
+			return nil, newChatError(409, i18n.T(c, "error.cannot_select_an_accommodation_now"), err)
+		}
+
+		var accommodation models.Accommodations
+		if err := db.Find(&accommodation, "accommodation_id = ?", answer.OptionID).Error; err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_find_accommodation"), err)
+		}
+		if accommodation.AccommodationID == "" {
+			return nil, newChatError(404, i18n.T(c, "error.accommodation_not_found"), nil)
+		}
+		return bookAccommodation(c, trip, accommodation)
+	}
+
+	if err := models.ValidateStageTransition(trip.Stage, models.StageChoosingAccommodation); err != nil {
+		return nil, newChatError(409, i18n.T(c, "error.cannot_select_a_flight_now"), err)
+	}
+
+	if answer.Option == chatparams.OptionMode {
+		legs, err := findModeFlights(answer.OptionID, chatHistories)
+		if err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_find_flight"), err)
+		}
+		if legs == nil {
+			return nil, newChatError(404, i18n.T(c, "error.mode_not_offered", answer.OptionID), nil)
+		}
+		return bookFlight(c, trip, legs...)
+	}
+
+	var flight models.Flights
+	if err := db.Find(&flight, "flight_id = ?", answer.OptionID).Error; err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_find_flight"), err)
+	}
+	if flight.FlightID == "" {
+		return nil, newChatError(404, i18n.T(c, "error.flight_not_found"), nil)
+	}
+
+	return bookFlight(c, trip, flight)
+}
+
+// findModeFlights returns the outbound and, if there is one, the return
+// flight of the latest trip plan for mode on the branch, or nil if no plan
+// for it was offered. It fails if the plan's return flight is not in the
+// catalog, so a round trip is never booked one way.
+func findModeFlights(mode string, chatHistories []models.ChatHistory) ([]models.Flights, error) {
+	for i := len(chatHistories) - 1; i >= 0; i-- {
+		var plans []models.TripPlans
+		if json.Unmarshal([]byte(chatHistories[i].FlightObject), &plans) != nil {
+			continue
+		}
+		for _, plan := range plans {
+			if plan.Mode != mode {
+				continue
+			}
+			outbound, err := findLeg(plan.SelectedFlight.OutboundFlight)
+			if err != nil || outbound == nil {
+				return nil, err
+			}
+
+			leg := plan.SelectedFlight.ReturnFlight
+			if leg.FlightNumber == nil {
+				return []models.Flights{*outbound}, nil
+			}
+			inbound, err := findLeg(leg)
+			if err != nil {
+				return nil, err
+			}
+			if inbound == nil {
+				return nil, fmt.Errorf("return flight %s of the %s plan not found", *leg.FlightNumber, mode)
+			}
+			return []models.Flights{*outbound, *inbound}, nil
+		}
+	}
+	return nil, nil
+}
+
+// findLeg returns the flight a leg of a trip plan refers to, or nil if there
+// is none.
+func findLeg(leg models.Flight) (*models.Flights, error) {
+	if leg.FlightNumber == nil {
+		return nil, nil
+	}
+
+	db := db.GetDB()
+	var flights []models.Flights
+	if err := db.Find(&flights, "flight_number = ?", *leg.FlightNumber).Error; err != nil {
+		return nil, fmt.Errorf("failed to find flight: %v", err)
+	}
+	for _, flight := range flights {
+		if leg.DepartureDate == nil || time.Time(flight.DepartureTime).Format("2006-01-02") == *leg.DepartureDate {
+			return &flight, nil
+		}
+	}
+	return nil, nil
+}
+
+// answerRequirements merges requirements the user answered directly, moving
+// the trip on to flights once they are complete. Changes that would
+// invalidate a booking wait for confirmation as they do when the agent
+// proposes them.
+func answerRequirements(c *gin.Context,
+	trip *models.Trip,
+	proposed models.Trip,
+	explicit map[string]bool,
+) (*models.ChatHistory, []models.TripChange, *chatError) {
+	if trip.Stage == models.StageConfirmed {
+		return nil, nil, newChatError(409, i18n.T(c, "error.trip_confirmed"), nil)
+	}
+
+	pending, err := mergeTripFields(trip, proposed, explicit)
+	if err != nil {
+		return nil, nil, newChatError(500, i18n.T(c, "error.failed_to_update_trip"), err)
+	}
+
+	locale := i18n.Locale(c)
+	message := i18n.Translate(locale, "chat.requirements_updated")
+	if len(pending) > 0 {
+		message = pendingChangesMessage(locale, pending)
+	} else if trip.Stage == models.StageCollectingRequirements && CheckDetailsComplete(trip) {
+		if err := setStage(trip, models.StageChoosingFlight); err != nil {
+			return nil, nil, newChatError(500, i18n.T(c, "error.failed_to_update_stage"), err)
+		}
+		message = i18n.Translate(locale, "chat.requirements_complete")
+	}
+
+	tripJSON, err := json.Marshal(trip)
+	if err != nil {
+		return nil, nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
+	}
+
+	resMsg := models.ChatHistory{
+		ChatHistoryID: trip.TripID,
+		ChatID:        uuid.New().String(),
+		UserID:        trip.UserID,
+		UserOrAgent:   "agent",
+		Message:       message,
+		ReqObject:     string(tripJSON),
+		Timestamp:     models.Now(),
+	}
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
+	}
+
+	return &resMsg, pending, nil
+}
+
+// answerConfirmation settles pending requirement changes or, when there are
+// none, confirms a trip under review.
+func answerConfirmation(c *gin.Context, trip *models.Trip, confirmed bool) (*models.ChatHistory, *chatError) {
+	if trip.Stage == models.StageConfirmed {
+		return nil, newChatError(409, i18n.T(c, "error.trip_confirmed"), nil)
+	}
+
+	changes, err := loadPendingChanges(trip.TripID)
+	if err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_find_pending_changes"), err)
+	}
+
+	locale := i18n.Locale(c)
+	switch {
+	case len(changes) > 0 && confirmed:
+		resMsg, err := confirmChanges(locale, trip, changes)
+		if err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_apply_changes"), err)
+		}
+		return resMsg, nil
+	case len(changes) > 0:
+		resMsg, err := rejectChanges(locale, trip)
+		if err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_reject_changes"), err)
+		}
+		return resMsg, nil
+	case trip.Stage != models.StageReviewing:
+		return nil, newChatError(409, i18n.T(c, "error.nothing_to_confirm"), nil)
+	}
+
+	message := i18n.Translate(locale, "chat.review_continues")
+	if confirmed {
+		if err := setStage(trip, models.StageConfirmed); err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_update_stage"), err)
+		}
+		message = i18n.Translate(locale, "chat.confirmed")
+	}
+
+	resMsg := models.ChatHistory{
+		ChatHistoryID: trip.TripID,
+		ChatID:        uuid.New().String(),
+		UserID:        trip.UserID,
+		UserOrAgent:   "agent",
+		Message:       message,
+		Timestamp:     models.Now(),
+	}
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
+	}
+
+	return &resMsg, nil
+}
@@ -0,0 +1,225 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// A conversation is a tree of messages linked by ParentID. The trip's
+// ActiveLeafID is the newest message of the branch the user is on; walking
+// parents from it gives the history that is shown and sent to the agent.
+// Requirements, bookings and stage belong to the trip and are shared by all
+// branches.
+
+// appendMessage stores msg as the next message on the conversation's active
+// branch and makes it the branch's new leaf.
+func appendMessage(trip *models.Trip, msg *models.ChatHistory) error {
+	return addMessage(trip, trip.ActiveLeafID, msg)
+}
+
+// addMessage stores msg as a reply to parentID, starting a new branch when
+// parentID already has replies, and makes it the active leaf.
+func addMessage(trip *models.Trip, parentID string, msg *models.ChatHistory) error {
+	db := db.GetDB()
+
+	msg.ParentID = parentID
+	if err := db.Create(msg).Error; err != nil {
+		return fmt.Errorf("failed to create chat history: %v", err)
+	}
+
+	return setActiveLeaf(trip, *msg)
+}
+
+// setActiveLeaf makes msg the active leaf and records it as the
+// conversation's latest activity.
+func setActiveLeaf(trip *models.Trip, msg models.ChatHistory) error {
+	db := db.GetDB()
+
+	preview := truncate(msg.Message, lastMessagePreviewLength)
+	if err := db.Model(trip).Updates(map[string]any{
+		"active_leaf_id":       msg.ChatID,
+		"last_activity_at":     msg.Timestamp,
+		"last_message_preview": preview,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update active branch: %v", err)
+	}
+	trip.ActiveLeafID = msg.ChatID
+	trip.LastActivityAt = msg.Timestamp
+	trip.LastMessagePreview = preview
+	return nil
+}
+
+// resolveBranch links the messages of a conversation written before
+// branching existed, oldest first, and persists the newest as its active
+// leaf. Conversations that already have an active leaf are left untouched.
+func resolveBranch(trip *models.Trip, chatHistories []models.ChatHistory) error {
+	if trip.ActiveLeafID != "" || len(chatHistories) == 0 {
+		return nil
+	}
+	db := db.GetDB()
+
+	for i := 1; i < len(chatHistories); i++ {
+		msg := &chatHistories[i]
+		if msg.ParentID != "" {
+			continue
+		}
+		msg.ParentID = chatHistories[i-1].ChatID
+		if err := db.Model(&models.ChatHistory{}).
+			Where("chat_history_id = ? AND chat_id = ?", msg.ChatHistoryID, msg.ChatID).
+			Update("parent_id", msg.ParentID).Error; err != nil {
+			return fmt.Errorf("failed to link chat history: %v", err)
+		}
+	}
+
+	return setActiveLeaf(trip, chatHistories[len(chatHistories)-1])
+}
+
+// activeBranch returns the messages from the root of the conversation to
+// leafID, oldest first.
+func activeBranch(chatHistories []models.ChatHistory, leafID string) []models.ChatHistory {
+	byID := make(map[string]int, len(chatHistories))
+	for i, msg := range chatHistories {
+		byID[msg.ChatID] = i
+	}
+
+	var branch []models.ChatHistory
+	for id := leafID; id != ""; {
+		i, ok := byID[id]
+		if !ok {
+			break
+		}
+		// Guard against a cycle in corrupted data.
+		delete(byID, id)
+		branch = append(branch, chatHistories[i])
+		id = chatHistories[i].ParentID
+	}
+	slices.Reverse(branch)
+
+	return branch
+}
+
+// RegenerateHandler answers the last user message on the active branch
+// again. The new answer starts a branch next to the previous one, which is
+// kept, and only becomes active once it has been generated.
+func RegenerateHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	chatHistories, err := loadChatHistories(trip)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	last := -1
+	for i := len(chatHistories) - 1; i >= 0 && last < 0; i-- {
+		if chatHistories[i].UserOrAgent == "user" {
+			last = i
+		}
+	}
+	// A structured answer was applied rather than answered by the agent, and
+	// a blocked message is never sent to it.
+	if last < 0 || chatHistories[last].ContentType != models.ContentText ||
+		chatHistories[last].Guardrail == guardrail.ActionBlock {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.nothing_to_regenerate")})
+		return
+	}
+	userMsg := chatHistories[last]
+
+	// The agent's answer is appended to the user message; the active leaf
+	// is only persisted with it.
+	trip.ActiveLeafID = userMsg.ChatID
+	body := chatparams.CreateParams{
+		ChatHistoryID: trip.TripID,
+		UserID:        userMsg.UserID,
+		Content:       userMsg.Message,
+	}
+
+	resView, chatErr := runTurn(c, trip, body, chatHistories[:last+1], noProgress)
+	if chatErr != nil {
+		c.JSON(chatErr.Status, gin.H{"error": chatErr.Message})
+		return
+	}
+
+	c.JSON(200, resView)
+}
+
+// EditMessageHandler replaces a user message on the active branch with a new
+// one, starting a branch from the message before it, and answers it.
+func EditMessageHandler(c *gin.Context) {
+	var body chatparams.EditParams
+
+	if err := c.Bind(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	chatHistories, err := loadChatHistories(trip)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	i := slices.IndexFunc(chatHistories, func(msg models.ChatHistory) bool {
+		return msg.ChatID == c.Param("chat_id")
+	})
+	if i < 0 {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.message_not_found")})
+		return
+	}
+	if chatHistories[i].UserOrAgent != "user" {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.not_user_message")})
+		return
+	}
+
+	params := chatparams.CreateParams{
+		ChatHistoryID: trip.TripID,
+		UserID:        userID,
+		Content:       body.Content,
+	}
+	model := guardMessage(&params, userID, "")
+	if err := addMessage(trip, chatHistories[i].ParentID, model); err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_create_chat_history") + ": " + err.Error()})
+		return
+	}
+
+	var resView *chatview.ChatResponse
+	var chatErr *chatError
+	if model.Guardrail == guardrail.ActionBlock {
+		resView, chatErr = blockedTurn(c, trip, model)
+	} else {
+		branch := append(chatHistories[:i:i], *model)
+		resView, chatErr = runTurn(c, trip, params, branch, noProgress)
+	}
+	if chatErr != nil {
+		c.JSON(chatErr.Status, gin.H{"error": chatErr.Message})
+		return
+	}
+
+	c.JSON(200, resView)
+}
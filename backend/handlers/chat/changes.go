@@ -0,0 +1,392 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// flightBookingFields are the requirements a flight booking depends on.
+var flightBookingFields = map[string]bool{
+	"OriginCity":         true,
+	"StartDate":          true,
+	"EndDate":            true,
+	"DestinationCountry": true,
+	"DestinationCities":  true,
+	"NumberOfTravelers":  true,
+	"AdultsCount":        true,
+	"ChildrenCount":      true,
+	"InfantsCount":       true,
+}
+
+// accommodationBookingFields are the requirements an accommodation booking
+// depends on.
+var accommodationBookingFields = map[string]bool{
+	"StartDate":         true,
+	"EndDate":           true,
+	"DestinationCities": true,
+	"NumberOfTravelers": true,
+	"AdultsCount":       true,
+	"ChildrenCount":     true,
+	"InfantsCount":      true,
+}
+
+// mergeRequirements applies the trip details proposed by the agent field by
+// field and records every change. Empty proposals are ignored. A change that
+// would invalidate an existing booking is stored as pending instead, and the
+// pending changes are returned so the user can be asked to confirm them.
+func mergeRequirements(trip *models.Trip, proposed models.Trip) ([]models.TripChange, error) {
+	return mergeTripFields(trip, proposed, nil)
+}
+
+// mergeTripFields is mergeRequirements where the fields named in explicit,
+// answered by the user directly, are applied even when empty, e.g. no
+// children.
+func mergeTripFields(trip *models.Trip, proposed models.Trip, explicit map[string]bool) ([]models.TripChange, error) {
+	db := db.GetDB()
+	hasFlight := HasFlightDetails(trip)
+	hasAccommodation := HasAccommodationDetails(trip)
+
+	tripValue := reflect.ValueOf(trip).Elem()
+	sourceValue := reflect.ValueOf(proposed)
+	sourceType := sourceValue.Type()
+
+	var pending []models.TripChange
+	for i := 0; i < sourceValue.NumField(); i++ {
+		fieldName := sourceType.Field(i).Name
+		if systemTripFields[fieldName] {
+			continue
+		}
+
+		sourceField := sourceValue.Field(i)
+		tripField := tripValue.Field(i)
+		if (sourceField.IsZero() && !explicit[fieldName]) || reflect.DeepEqual(tripField.Interface(), sourceField.Interface()) {
+			continue
+		}
+
+		oldValue, err := json.Marshal(tripField.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal field %s: %v", fieldName, err)
+		}
+		newValue, err := json.Marshal(sourceField.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal field %s: %v", fieldName, err)
+		}
+
+		change := models.TripChange{
+			ChangeID: uuid.New().String(),
+			TripID:   trip.TripID,
+			UserID:   trip.UserID,
+			Field:    jsonFieldName(sourceType.Field(i)),
+			OldValue: string(oldValue),
+			NewValue: string(newValue),
+			Status:   models.ChangeApplied,
+		}
+		// Filling in a missing requirement never invalidates a booking.
+		if !tripField.IsZero() {
+			change.InvalidatesFlight = hasFlight && flightBookingFields[fieldName]
+			change.InvalidatesAccommodation = hasAccommodation && accommodationBookingFields[fieldName]
+		}
+
+		if change.InvalidatesFlight || change.InvalidatesAccommodation {
+			change.Status = models.ChangePending
+			// A newer proposal for the same field replaces any still pending.
+			superseded, err := loadPendingChanges(trip.TripID)
+			if err != nil {
+				return nil, err
+			}
+			superseded = slices.DeleteFunc(superseded, func(other models.TripChange) bool {
+				return other.Field != change.Field
+			})
+			if err := setChangeStatus(superseded, models.ChangeRejected); err != nil {
+				return nil, err
+			}
+			pending = append(pending, change)
+		} else {
+			// Update this specific field in the database
+			if err := db.Model(trip).Update(fieldName, sourceField.Interface()).Error; err != nil {
+				return nil, fmt.Errorf("failed to update field %s: %v", fieldName, err)
+			}
+			tripField.Set(sourceField)
+		}
+
+		if err := db.Create(&change).Error; err != nil {
+			return nil, fmt.Errorf("failed to record change to %s: %v", change.Field, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// applyTripChange sets the field named by a recorded change to its new value.
+func applyTripChange(trip *models.Trip, change models.TripChange) error {
+	tripValue := reflect.ValueOf(trip).Elem()
+	tripType := tripValue.Type()
+
+	for i := 0; i < tripType.NumField(); i++ {
+		if jsonFieldName(tripType.Field(i)) != change.Field || systemTripFields[tripType.Field(i).Name] {
+			continue
+		}
+
+		field := tripValue.Field(i)
+		value := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(change.NewValue), value.Interface()); err != nil {
+			return fmt.Errorf("failed to decode new value for %s: %v", change.Field, err)
+		}
+		db := db.GetDB()
+		if err := db.Model(trip).Update(tripType.Field(i).Name, value.Elem().Interface()).Error; err != nil {
+			return fmt.Errorf("failed to update field %s: %v", change.Field, err)
+		}
+		field.Set(value.Elem())
+		return nil
+	}
+
+	return fmt.Errorf("unknown trip field %s", change.Field)
+}
+
+// invalidateBookings flags the trip's active bookings that no longer fit
+// its requirements.
+func invalidateBookings(trip *models.Trip, flight bool, accommodation bool, reason string) error {
+	db := db.GetDB()
+	updates := map[string]any{"invalidated": true, "invalidated_reason": reason, "updated_at": models.Now()}
+
+	if flight {
+		var bookings []models.FlightBookings
+		if err := db.Find(&bookings, "trip_id = ?", trip.TripID).Error; err != nil {
+			return fmt.Errorf("failed to find flight bookings: %v", err)
+		}
+		for _, booking := range bookings {
+			if booking.Invalidated {
+				continue
+			}
+			if err := db.Model(&booking).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to invalidate flight booking: %v", err)
+			}
+		}
+	}
+
+	if accommodation {
+		var bookings []models.AccommodationBookings
+		if err := db.Find(&bookings, "trip_id = ?", trip.TripID).Error; err != nil {
+			return fmt.Errorf("failed to find accommodation bookings: %v", err)
+		}
+		for _, booking := range bookings {
+			if booking.Invalidated {
+				continue
+			}
+			if err := db.Model(&booking).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to invalidate accommodation booking: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func loadPendingChanges(tripID string) ([]models.TripChange, error) {
+	db := db.GetDB()
+
+	var changes []models.TripChange
+	if err := db.Where("trip_id = ? AND status = ?", tripID, models.ChangePending).Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to find pending changes: %v", err)
+	}
+
+	return changes, nil
+}
+
+// setChangeStatus updates recorded changes one at a time, each by its full
+// key, as DynamoDB cannot update items matched on other attributes.
+func setChangeStatus(changes []models.TripChange, status string) error {
+	db := db.GetDB()
+
+	for _, change := range changes {
+		if err := db.Model(&change).Updates(map[string]any{"status": status, "updated_at": models.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to update change: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// pendingChangesMessage asks the user to confirm changes that would
+// invalidate their bookings.
+func pendingChangesMessage(locale string, changes []models.TripChange) string {
+	var lines []string
+	flight, accommodation := false, false
+	for _, change := range changes {
+		lines = append(lines, i18n.Translate(locale, "chat.change", change.Field, change.OldValue, change.NewValue))
+		flight = flight || change.InvalidatesFlight
+		accommodation = accommodation || change.InvalidatesAccommodation
+	}
+
+	var bookings []string
+	if flight {
+		bookings = append(bookings, i18n.Translate(locale, "booking.flight"))
+	}
+	if accommodation {
+		bookings = append(bookings, i18n.Translate(locale, "booking.accommodation"))
+	}
+
+	return i18n.Translate(locale, "chat.pending_changes", i18n.List(locale, lines), i18n.List(locale, bookings))
+}
+
+// ConfirmChangesHandler applies a conversation's pending requirement
+// changes, flags the bookings they invalidate and moves the trip back to
+// the stage where those bookings are made again.
+func ConfirmChangesHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+	if trip.Stage == models.StageConfirmed {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.trip_confirmed")})
+		return
+	}
+
+	changes, err := loadPendingChanges(trip.TripID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if len(changes) == 0 {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.no_pending_changes_to_confirm")})
+		return
+	}
+
+	resMsg, err := confirmChanges(i18n.Locale(c), trip, changes)
+	if err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_apply_changes") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, chatview.ChatResponse{
+		ConversationID: trip.TripID,
+		Content:        resMsg.Message,
+		Stage:          trip.Stage,
+		CreatedAt:      resMsg.Timestamp.ToString(),
+		IsUser:         false,
+	})
+}
+
+// confirmChanges applies pending changes and records the outcome in the
+// conversation.
+func confirmChanges(locale string, trip *models.Trip, changes []models.TripChange) (*models.ChatHistory, error) {
+	flight, accommodation := false, false
+	var fields []string
+	for _, change := range changes {
+		if err := applyTripChange(trip, change); err != nil {
+			return nil, err
+		}
+		flight = flight || change.InvalidatesFlight
+		accommodation = accommodation || change.InvalidatesAccommodation
+		fields = append(fields, change.Field)
+	}
+
+	if err := setChangeStatus(changes, models.ChangeApplied); err != nil {
+		return nil, err
+	}
+
+	reason := "Trip changed: " + strings.Join(fields, ", ")
+	if err := invalidateBookings(trip, flight, accommodation, reason); err != nil {
+		return nil, err
+	}
+
+	target := trip.Stage
+	if flight {
+		target = models.StageChoosingFlight
+	} else if accommodation {
+		target = models.StageChoosingAccommodation
+	}
+	if target != trip.Stage && models.ValidateStageRewind(trip.Stage, target) == nil {
+		if err := rewindStage(trip, target); err != nil {
+			return nil, err
+		}
+	}
+
+	resMsg := models.ChatHistory{
+		ChatHistoryID: trip.TripID,
+		ChatID:        uuid.New().String(),
+		UserID:        trip.UserID,
+		UserOrAgent:   "agent",
+		Message:       i18n.Translate(locale, "chat.changes_applied", i18n.List(locale, fields), i18n.Translate(locale, "stage."+trip.Stage)),
+		Timestamp:     models.Now(),
+	}
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, fmt.Errorf("failed to create chat response: %v", err)
+	}
+
+	return &resMsg, nil
+}
+
+// RejectChangesHandler discards a conversation's pending requirement changes.
+func RejectChangesHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	resMsg, err := rejectChanges(i18n.Locale(c), trip)
+	if err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_reject_changes") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, chatview.ChatResponse{
+		ConversationID: trip.TripID,
+		Content:        resMsg.Message,
+		Stage:          trip.Stage,
+		CreatedAt:      resMsg.Timestamp.ToString(),
+		IsUser:         false,
+	})
+}
+
+func rejectChanges(locale string, trip *models.Trip) (*models.ChatHistory, error) {
+	changes, err := loadPendingChanges(trip.TripID)
+	if err != nil {
+		return nil, err
+	}
+	if err := setChangeStatus(changes, models.ChangeRejected); err != nil {
+		return nil, err
+	}
+
+	resMsg := models.ChatHistory{
+		ChatHistoryID: trip.TripID,
+		ChatID:        uuid.New().String(),
+		UserID:        trip.UserID,
+		UserOrAgent:   "agent",
+		Message:       i18n.Translate(locale, "chat.changes_rejected"),
+		Timestamp:     models.Now(),
+	}
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, fmt.Errorf("failed to create chat response: %v", err)
+	}
+
+	return &resMsg, nil
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
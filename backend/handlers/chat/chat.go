@@ -2,12 +2,16 @@ package chat
 
 import (
 	"encoding/json"
-	"fmt"
+	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/accommodations"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/flights"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/usage"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/lda"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
 	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
@@ -21,81 +25,204 @@ func ChatHandler(c *gin.Context) {
 		return
 	}
 
-	db := db.GetDB()
-	model := body.ToModel(body.UserID, "")
-
-	if err := db.Create(model).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create chat history: " + err.Error()})
+	trip, ok := authorizeTurn(c, &body)
+	if !ok {
 		return
 	}
 
-	var trip models.Trip
-	if err := db.Find(&trip, "trip_id = ?", body.ChatHistoryID).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find trip: " + err.Error()})
+	resView, chatErr := processChat(c, trip, body, noProgress)
+	if chatErr != nil {
+		c.JSON(chatErr.Status, gin.H{"error": chatErr.Message})
 		return
 	}
 
-	var chatHistories []models.ChatHistory
-	if err := db.Where("chat_history_id = ?", body.ChatHistoryID).Order("created_at asc").Find(&chatHistories).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find chat histories: " + err.Error()})
-		return
+	c.JSON(200, resView)
+}
+
+// processChat runs one chat turn through the requirement, flight and
+// accommodation stages, reporting each stage to emit as it starts. Structured
+// answers are applied directly instead, and messages the guardrails block
+// get a canned reply. It is shared by the plain and the
+// streaming chat endpoints.
+func processChat(c *gin.Context, trip *models.Trip, body chatparams.CreateParams, emit progress) (*chatview.ChatResponse, *chatError) {
+	if err := body.Validate(); err != nil {
+		return nil, newChatError(400, i18n.T(c, "error.validation_failed"), err)
+	}
+
+	chatHistories, err := loadChatHistories(trip)
+	if err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_load_chat_history"), err)
+	}
+
+	var object any = ""
+	if body.ContentType != models.ContentText {
+		object = body.Answer
+	}
+	model := guardMessage(&body, body.UserID, object)
+	if err := appendMessage(trip, model); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_history"), err)
+	}
+	chatHistories = append(chatHistories, *model)
+
+	if model.Guardrail == guardrail.ActionBlock {
+		return blockedTurn(c, trip, model)
+	}
+	if body.ContentType != models.ContentText {
+		return answerTurn(c, trip, body, chatHistories)
+	}
+	return runTurn(c, trip, body, chatHistories, emit)
+}
+
+// authorizeTurn loads the conversation of a chat turn if it belongs to the
+// signed-in user, who the message is then attributed to. It writes the error
+// response itself otherwise.
+func authorizeTurn(c *gin.Context, body *chatparams.CreateParams) (*models.Trip, bool) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return nil, false
 	}
 
+	trip, ok := findOwnedTrip(c, body.ChatHistoryID, userID)
+	if !ok {
+		return nil, false
+	}
+
+	body.UserID = userID
+	return trip, true
+}
+
+// runTurn answers the last message of chatHistories, a user message on the
+// trip's active branch, and appends the answer to that branch.
+func runTurn(c *gin.Context,
+	trip *models.Trip,
+	body chatparams.CreateParams,
+	chatHistories []models.ChatHistory,
+	emit progress,
+) (*chatview.ChatResponse, *chatError) {
+	if _, err := resolveStage(trip); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_resolve_stage"), err)
+	}
+
+	// Progress events may be emitted from several goroutines, so the locale
+	// is resolved once up front.
+	locale := i18n.Locale(c)
+	trace := &lda.Trace{}
+	c.Request = c.Request.WithContext(lda.WithTrace(c.Request.Context(), trace))
+	// Calls cost the same whether or not the turn goes on to fail.
+	defer func() {
+		if err := usage.Record(trip.UserID, trip.TripID, trace); err != nil {
+			log.Printf("Failed to store usage of conversation %s: %v", trip.TripID, err)
+		}
+	}()
 	var retRes *FinalResponse
 	var err error
 
-	if !CheckDetailsComplete(&trip) {
-		retRes, err = getRequirements(c, &trip, body, &chatHistories)
+	// Requirements are parsed on every turn until the trip is confirmed, so
+	// they can still be changed once flights or accommodation are chosen.
+	if trip.Stage != models.StageConfirmed {
+		emit(EventStage, stageEvent(StageParsingRequirements, i18n.Translate(locale, "progress.parsing_requirements")))
+		retRes, err = getRequirements(c, trip, body, &chatHistories)
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to get requirements: " + err.Error()})
-			return
+			return nil, newChatError(agentErrorStatus(err), i18n.T(c, "error.failed_to_get_requirements"), err)
+		}
+
+		if trip.Stage == models.StageCollectingRequirements && CheckDetailsComplete(trip) {
+			if err := setStage(trip, models.StageChoosingFlight); err != nil {
+				return nil, newChatError(500, i18n.T(c, "error.failed_to_update_stage"), err)
+			}
 		}
 	}
 
-	if CheckDetailsComplete(&trip) && !HasFlightDetails(&trip) {
-		fmt.Println("Getting flight details...")
-		// Get flights based on trip dates (assuming trip has DepartureDate and ReturnDate fields)
-		flights, err := flights.GetFlightsByDateRange(trip.StartDate, trip.EndDate)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to get flights: " + err.Error()})
-			return
+	// Changes that would invalidate a booking wait for the user to confirm
+	// them before the conversation moves on.
+	awaitingConfirmation := retRes != nil && len(retRes.PendingChanges) > 0
+	if awaitingConfirmation {
+		retRes.Response = pendingChangesMessage(locale, retRes.PendingChanges)
+	}
+
+	if !awaitingConfirmation && trip.Stage == models.StageChoosingFlight {
+		emit(EventStage, stageEvent(StageSearchingFlights, i18n.Translate(locale, "progress.searching_flights")))
+		// With tools the agent searches flights itself.
+		var candidates *flights.Candidates
+		if !toolsEnabled() {
+			candidates, err = flightCandidates(trip)
+			if err != nil {
+				return nil, newChatError(500, i18n.T(c, "error.failed_to_get_flights"), err)
+			}
 		}
 
-		retRes, err = getFlight(c, &trip, body, &chatHistories, &flights)
+		retRes, err = getFlight(c, trip, body, &chatHistories, candidates, emit)
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to get flight response: " + err.Error()})
-			return
+			return nil, newChatError(agentErrorStatus(err), i18n.T(c, "error.failed_to_get_flight_response"), err)
+		}
+	}
+
+	if !awaitingConfirmation && trip.Stage == models.StageChoosingAccommodation {
+		emit(EventStage, stageEvent(StageChoosingStay, i18n.Translate(locale, "progress.choosing_accommodation")))
+		if !lda.AccommodationConfigured() {
+			return nil, newChatError(503, i18n.T(c, "error.accommodation_agent_not_configured"), nil)
+		}
+		// With tools the agent searches accommodation itself.
+		var accoms []models.Accommodations
+		if !toolsEnabled() {
+			accoms, err = accommodations.FindAvailable(trip)
+			if err != nil {
+				return nil, newChatError(500, i18n.T(c, "error.failed_to_get_accommodations"), err)
+			}
+		}
+
+		if len(accoms) == 0 && !toolsEnabled() {
+			retRes = &FinalResponse{
+				Response: i18n.Translate(locale, "chat.no_accommodation", i18n.List(locale, trip.DestinationCities)),
+			}
+		} else {
+			retRes, err = GetAccomodations(c, trip, body, &chatHistories, &accoms)
+			if err != nil {
+				return nil, newChatError(agentErrorStatus(err), i18n.T(c, "error.failed_to_get_accommodation_response"), err)
+			}
+		}
+	}
+
+	if !awaitingConfirmation && trip.Stage == models.StageReviewing {
+		retRes = &FinalResponse{
+			Response: i18n.Translate(locale, "chat.reviewing"),
+		}
+	}
+
+	if trip.Stage == models.StageConfirmed {
+		retRes = &FinalResponse{
+			Response: i18n.Translate(locale, "chat.confirmed"),
 		}
 	}
 
 	if retRes == nil {
-		c.JSON(400, gin.H{"error": "No response generated"})
-		return
+		return nil, newChatError(400, i18n.T(c, "error.no_response_generated"), nil)
 	}
 
 	reqJSON, err := json.Marshal(retRes.TripDetails)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal response: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
 	}
 
 	flightJSON, err := json.Marshal(retRes.TripOptions)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal response: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
 	}
-	fmt.Println("Flight JSON:", string(flightJSON))
 
-	accomJSON, err := json.Marshal(retRes.AccomodationDetails)
+	var accomJSON []byte
+	if len(retRes.AccommodationOptions) > 0 {
+		accomJSON, err = json.Marshal(retRes.AccommodationOptions)
+	} else {
+		accomJSON, err = json.Marshal(retRes.AccomodationDetails)
+	}
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal response: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
 	}
 
 	currTime := models.Now()
 
 	resMsg := models.ChatHistory{
-		ChatHistoryID:       model.ChatHistoryID,
+		ChatHistoryID:       trip.TripID,
 		ChatID:              uuid.New().String(),
 		UserID:              body.UserID,
 		UserOrAgent:         "agent",
@@ -106,20 +233,23 @@ func ChatHandler(c *gin.Context) {
 		Timestamp:           currTime,
 	}
 
-	if err := db.Create(&resMsg).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create chat response: " + err.Error()})
-		return
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
+	}
+	if err := saveTrace(trip, resMsg.ChatID, trace); err != nil {
+		log.Printf("Failed to store trace of chat %s: %v", resMsg.ChatID, err)
 	}
 
-	resView := chatview.ChatResponse{
+	return &chatview.ChatResponse{
 		ConversationID:      body.ChatHistoryID,
 		Content:             retRes.Response,
 		Object:              string(reqJSON),
 		FlightObject:        string(flightJSON),
 		AccommodationObject: string(accomJSON),
+		ModeErrors:          retRes.ModeErrors,
+		PendingChanges:      retRes.PendingChanges,
+		Stage:               trip.Stage,
 		CreatedAt:           currTime.ToString(),
 		IsUser:              false,
-	}
-
-	c.JSON(200, resView)
+	}, nil
 }
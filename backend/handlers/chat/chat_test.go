@@ -0,0 +1,379 @@
+package chat_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/lda"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/router"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fixtures holds the recorded agent calls the tests replay. To record them
+// again, e.g. after a payload changes, run the tests with AGENT_RECORD set
+// to this directory; AGENT_BACKEND picks where the answers come from and
+// defaults to the stub handlers, see recordingStubs:
+//
+//	AGENT_RECORD=testdata/fixtures go test ./handlers/chat
+const fixtures = "testdata/fixtures"
+
+// tables are created with the models' primary keys, which match the
+// DynamoDB tables, so a write that would overwrite or collide with another
+// item there fails here too.
+var tables = []any{
+	&models.Users{},
+	&models.Trip{},
+	&models.ChatHistory{},
+	&models.Airports{},
+	&models.Flights{},
+	&models.FlightBookings{},
+	&models.Accommodations{},
+	&models.AccommodationBookings{},
+	&models.ConversationSummary{},
+	&models.TripChange{},
+	&models.Itinerary{},
+	&models.IdempotencyRecord{},
+	&models.AgentTrace{},
+	&models.MessageFeedback{},
+	&models.AgentUsage{},
+	&models.DailyUsage{},
+}
+
+// TestChatHandler walks a conversation through the requirement, flight and
+// accommodation stages against replayed agent answers, which are checked
+// and counted as live ones are. Every turn runs well within a second, so a
+// message and its reply share a timestamp and must be stored under distinct
+// keys.
+func TestChatHandler(t *testing.T) {
+	gdb := openStore(t)
+	initAgent(t)
+	if err := guardrail.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.Setup(r)
+
+	user := seed(t, gdb)
+	token, err := myjwt.GenerateJWTToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	code, body := request(r, http.MethodPost, "/chat/create", token, nil)
+	if code != http.StatusOK || json.Unmarshal(body, &created) != nil {
+		t.Fatalf("create conversation: %d %s", code, body)
+	}
+
+	// stays are the accommodations a turn recommends, if it is expected to.
+	turns := []struct {
+		content string
+		answer  *chatparams.Answer
+		stage   string
+		stays   []string
+	}{
+		{`{"trip_name": "Tokyo for two", "origin_city": "Singapore", "destination_country": "Japan", "destination_cities": "Tokyo", "landmarks": "Shibuya", "start_date": "2030-05-01T00:00:00Z", "end_date": "2030-05-08", "total_budget": 3000, "number_of_travelers": 2, "adults_count": 2, "trip_type": "couple", "purpose": "anniversary", "notes": "quiet hotels", "dietary_restrictions": "vegetarian"}`, nil, models.StageChoosingFlight, nil},
+		{"Show me flights", nil, models.StageChoosingFlight, nil},
+		{"Chill", &chatparams.Answer{Option: chatparams.OptionMode, OptionID: "chill"}, models.StageChoosingAccommodation, nil},
+		{"Somewhere quiet near Shibuya", nil, models.StageChoosingAccommodation, []string{"tokyo-inn"}},
+		{"Tokyo Inn", &chatparams.Answer{Option: chatparams.OptionAccommodation, OptionID: "tokyo-inn"}, models.StageReviewing, nil},
+	}
+	for i, turn := range turns {
+		params := chatparams.CreateParams{
+			ChatHistoryID: created.ConversationID,
+			UserID:        user.UserID,
+			Content:       turn.content,
+			Answer:        turn.answer,
+		}
+		if turn.answer != nil {
+			params.ContentType = models.ContentOptionSelection
+		}
+		code, body := request(r, http.MethodPost, "/chat/", token, params)
+		if code != http.StatusOK {
+			t.Fatalf("turn %d: %d %s", i+1, code, body)
+		}
+
+		var res struct {
+			Stage               string `json:"stage"`
+			AccommodationObject string `json:"accommodation_object"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("turn %d: %v", i+1, err)
+		}
+		if res.Stage != turn.stage {
+			t.Fatalf("turn %d: stage %q, want %q", i+1, res.Stage, turn.stage)
+		}
+		if turn.stays == nil {
+			continue
+		}
+
+		// Only stays that were offered are passed on, with their record.
+		var options []models.AccommodationRecommendation
+		if err := json.Unmarshal([]byte(res.AccommodationObject), &options); err != nil {
+			t.Fatalf("turn %d: accommodation options: %v", i+1, err)
+		}
+		stays := make([]string, 0, len(options))
+		for _, option := range options {
+			if option.Accommodation == nil || option.Accommodation.AccommodationID != option.AccommodationID {
+				t.Errorf("turn %d: option %s without its accommodation", i+1, option.AccommodationID)
+			}
+			stays = append(stays, option.AccommodationID)
+		}
+		if !slices.Equal(stays, turn.stays) {
+			t.Errorf("turn %d: recommended stays %v, want %v", i+1, stays, turn.stays)
+		}
+	}
+
+	var messages struct {
+		Messages []struct {
+			ChatID string `json:"chat_id"`
+			IsUser bool   `json:"is_user"`
+		} `json:"messages"`
+	}
+	code, body = request(r, http.MethodGet, "/chat/"+created.ConversationID+"/messages", token, nil)
+	if code != http.StatusOK || json.Unmarshal(body, &messages) != nil {
+		t.Fatalf("list messages: %d %s", code, body)
+	}
+	if len(messages.Messages) != 2*len(turns) {
+		t.Fatalf("got %d messages, want %d", len(messages.Messages), 2*len(turns))
+	}
+	for i, msg := range messages.Messages {
+		if msg.IsUser != (i%2 == 0) {
+			t.Errorf("message %d (%s): is_user %v", i, msg.ChatID, msg.IsUser)
+		}
+	}
+
+	var flights []models.FlightBookings
+	if err := gdb.Find(&flights, "trip_id = ?", created.ConversationID).Error; err != nil {
+		t.Fatal(err)
+	}
+	booked := make([]string, 0, len(flights))
+	for _, booking := range flights {
+		booked = append(booked, booking.FlightID)
+	}
+	slices.Sort(booked)
+	if want := []string{"SQ637-0508", "SQ638-0501"}; !slices.Equal(booked, want) {
+		t.Errorf("booked flights %v, want %v", booked, want)
+	}
+
+	var stays []models.AccommodationBookings
+	if err := gdb.Find(&stays, "trip_id = ?", created.ConversationID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stays) != 1 || stays[0].AccommodationID != "tokyo-inn" {
+		t.Errorf("accommodation bookings: %+v", stays)
+	}
+
+	var usage []models.AgentUsage
+	if err := gdb.Find(&usage, "trip_id = ?", created.ConversationID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var counter models.DailyUsage
+	if err := gdb.Find(&counter, "user_id = ?", user.UserID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) == 0 || counter.Calls != len(usage) {
+		t.Errorf("recorded %d agent calls, counted %d", len(usage), counter.Calls)
+	}
+}
+
+// openStore opens an empty in-memory database with every table and makes it
+// the one the handlers use.
+func openStore(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", uuid.New().String())
+	gdb, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := gdb.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	db.SetDB(gdb)
+	return gdb
+}
+
+// initAgent replays the fixtures, or records them when AGENT_RECORD is set.
+func initAgent(t *testing.T) {
+	t.Helper()
+
+	if os.Getenv("AGENT_ACCOMMODATION_FUNCTION") == "" {
+		t.Setenv("AGENT_ACCOMMODATION_FUNCTION", "accommodation_decider")
+	}
+	record := os.Getenv("AGENT_RECORD")
+	stubbed := record != "" && os.Getenv("AGENT_BACKEND") == ""
+	if record == "" {
+		t.Setenv("AGENT_BACKEND", lda.BackendReplay)
+		t.Setenv("AGENT_FIXTURES", fixtures)
+	} else if stubbed {
+		t.Setenv("AGENT_BACKEND", lda.BackendLocal)
+	}
+	if err := lda.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if stubbed {
+		lda.SetAgent(lda.NewRecordingAgent(lda.NewInProcessAgent(recordingStubs()), record))
+	}
+}
+
+// recordingStubs are the stub handlers, except that the accommodation
+// decider also recommends a stay it was not offered, which the handler has
+// to drop.
+func recordingStubs() map[string]lda.HandlerFunc {
+	handlers := lda.StubHandlers()
+	decide := handlers[*lda.ACCOMMODATION]
+	handlers[*lda.ACCOMMODATION] = func(ctx context.Context, payload lda.LambdaPayload) (string, error) {
+		body, err := decide(ctx, payload)
+		if err != nil {
+			return "", err
+		}
+
+		var text lda.TextResponse
+		if err := json.Unmarshal([]byte(body), &text); err != nil {
+			return "", fmt.Errorf("failed to parse stub response: %v", err)
+		}
+		fenced := strings.TrimSuffix(strings.TrimPrefix(text.Response, "```json\n"), "\n```")
+		var answer map[string]any
+		if err := json.Unmarshal([]byte(fenced), &answer); err != nil {
+			return "", fmt.Errorf("failed to parse stub answer: %v", err)
+		}
+		options, _ := answer["accommodation_options"].([]any)
+		answer["accommodation_options"] = append(options, map[string]any{
+			"accommodation_id": "osaka-inn",
+			"reason":           "Not on offer.",
+		})
+
+		answerJSON, err := json.Marshal(answer)
+		if err != nil {
+			return "", err
+		}
+		text.Response = "```json\n" + string(answerJSON) + "\n```"
+		data, err := json.Marshal(text)
+		return string(data), err
+	}
+	return handlers
+}
+
+// seed stores the user and the catalog the conversation books from.
+func seed(t *testing.T, gdb *gorm.DB) models.Users {
+	t.Helper()
+
+	user := models.Users{
+		UserID:      uuid.New().String(),
+		Username:    "chattest",
+		Email:       "chattest@example.com",
+		Nationality: "Singaporean",
+	}
+	records := []any{
+		&user,
+		&models.Airports{AirportCode: "SIN", City: "Singapore", Country: "Singapore"},
+		&models.Airports{AirportCode: "NRT", City: "Tokyo", Country: "Japan"},
+		&models.Flights{
+			FlightID:         "SQ638-0501",
+			FlightNumber:     "SQ638",
+			Airline:          "Singapore Airlines",
+			DepartureAirport: "SIN",
+			ArrivalAirport:   "NRT",
+			DepartureTime:    mustTime(t, "2030-05-01T08:00:00Z"),
+			ArrivalTime:      mustTime(t, "2030-05-01T15:00:00Z"),
+			DurationMinutes:  420,
+			AvailableSeats:   20,
+			PriceEconomy:     450,
+			Layovers:         "Direct",
+			Status:           "Scheduled",
+		},
+		&models.Flights{
+			FlightID:         "SQ637-0508",
+			FlightNumber:     "SQ637",
+			Airline:          "Singapore Airlines",
+			DepartureAirport: "NRT",
+			ArrivalAirport:   "SIN",
+			DepartureTime:    mustTime(t, "2030-05-08T11:00:00Z"),
+			ArrivalTime:      mustTime(t, "2030-05-08T18:00:00Z"),
+			DurationMinutes:  420,
+			AvailableSeats:   20,
+			PriceEconomy:     430,
+			Layovers:         "Direct",
+			Status:           "Scheduled",
+		},
+		&models.Accommodations{
+			AccommodationID:  "tokyo-inn",
+			Name:             "Tokyo Inn",
+			Type:             "Hotel",
+			City:             "Tokyo",
+			Country:          "Japan",
+			StarRating:       4,
+			TotalRooms:       10,
+			MaxGuestsPerRoom: 2,
+		},
+		&models.Accommodations{
+			AccommodationID:  "osaka-inn",
+			Name:             "Osaka Inn",
+			Type:             "Hotel",
+			City:             "Osaka",
+			Country:          "Japan",
+			StarRating:       4,
+			TotalRooms:       10,
+			MaxGuestsPerRoom: 2,
+		},
+	}
+	for _, record := range records {
+		if err := gdb.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user
+}
+
+func mustTime(t *testing.T, value string) models.RFC3339Time {
+	t.Helper()
+
+	var parsed models.RFC3339Time
+	if err := json.Unmarshal([]byte(`"`+value+`"`), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func request(r *gin.Engine, method, path, token string, body any) (int, []byte) {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 )
@@ -13,24 +14,26 @@ import (
 func CreateHandler(c *gin.Context) {
 	claims, err := myjwt.ParseJWTFromContext(c)
 	if err != nil {
-		c.JSON(403, gin.H{"error": "Unauthorized: " + err.Error()})
+		c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
 		return
 	}
 
 	db := db.GetDB()
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		c.JSON(400, gin.H{"error": "Invalid user ID in token"})
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
 		return
 	}
 	newTrip := models.Trip{
 		TripID:    uuid.New().String(),
 		UserID:    userID,
 		StartDate: time.Now().Format(time.RFC3339),
+		Stage:     models.StageCollectingRequirements,
+		CreatedAt: models.Now(),
 	}
 
 	if err := db.Create(&newTrip).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create trip: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_create_trip") + ": " + err.Error()})
 		return
 	}
 
@@ -0,0 +1,144 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// FeedbackHandler rates an agent message thumbs up or down, with an optional
+// reason category and comment. Rating a message again replaces its rating.
+func FeedbackHandler(c *gin.Context) {
+	var body chatparams.FeedbackParams
+
+	if err := c.Bind(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err := body.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.validation_failed") + ": " + err.Error()})
+		return
+	}
+
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	db := db.GetDB()
+	chatID := c.Param("chat_id")
+
+	var msg models.ChatHistory
+	if err := db.Find(&msg, "chat_history_id = ? AND chat_id = ?", trip.TripID, chatID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_message") + ": " + err.Error()})
+		return
+	}
+	if msg.ChatID == "" {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.message_not_found")})
+		return
+	}
+	if msg.UserOrAgent != "agent" {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.not_agent_message")})
+		return
+	}
+
+	var feedback models.MessageFeedback
+	if err := db.Find(&feedback, "trip_id = ? AND chat_id = ?", trip.TripID, chatID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_feedback") + ": " + err.Error()})
+		return
+	}
+
+	if feedback.ChatID == "" {
+		feedback = models.MessageFeedback{
+			TripID:  trip.TripID,
+			ChatID:  chatID,
+			UserID:  userID,
+			Rating:  body.Rating,
+			Reason:  body.Reason,
+			Comment: body.Comment,
+		}
+		if err := db.Create(&feedback).Error; err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_save_feedback") + ": " + err.Error()})
+			return
+		}
+	} else {
+		feedback.Rating = body.Rating
+		feedback.Reason = body.Reason
+		feedback.Comment = body.Comment
+		feedback.UpdatedAt = models.Now()
+		if err := db.Model(&feedback).Updates(map[string]any{
+			"rating":     feedback.Rating,
+			"reason":     feedback.Reason,
+			"comment":    feedback.Comment,
+			"updated_at": feedback.UpdatedAt,
+		}).Error; err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_save_feedback") + ": " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(200, chatview.NewFeedbackResponse(feedback))
+}
+
+// ExportFeedback writes every agent message rated since the given time, or
+// only those with the given rating if it is set, as JSON lines of
+// chatview.FeedbackExport, oldest rating first. Each line carries the user
+// message it answered and the requests that produced it, so the agents can
+// be evaluated against real answers. It returns the number of lines written.
+func ExportFeedback(w io.Writer, rating string, since time.Time) (int, error) {
+	db := db.GetDB()
+
+	query := db.Where("updated_at >= ?", models.RFC3339Time(since.UTC()))
+	if rating != "" {
+		query = query.Where("rating = ?", rating)
+	}
+	var ratings []models.MessageFeedback
+	if err := query.Find(&ratings).Error; err != nil {
+		return 0, fmt.Errorf("failed to find feedback: %v", err)
+	}
+	sort.Slice(ratings, func(i, j int) bool {
+		return time.Time(ratings[i].UpdatedAt).Before(time.Time(ratings[j].UpdatedAt))
+	})
+
+	encoder := json.NewEncoder(w)
+	written := 0
+	for _, feedback := range ratings {
+		var msg models.ChatHistory
+		if err := db.Find(&msg, "chat_history_id = ? AND chat_id = ?", feedback.TripID, feedback.ChatID).Error; err != nil {
+			return written, fmt.Errorf("failed to find message: %v", err)
+		}
+
+		var prompt models.ChatHistory
+		if msg.ParentID != "" {
+			if err := db.Find(&prompt, "chat_history_id = ? AND chat_id = ?", feedback.TripID, msg.ParentID).Error; err != nil {
+				return written, fmt.Errorf("failed to find message: %v", err)
+			}
+		}
+
+		var trace models.AgentTrace
+		if err := db.Find(&trace, "trip_id = ? AND chat_id = ?", feedback.TripID, feedback.ChatID).Error; err != nil {
+			return written, fmt.Errorf("failed to find trace: %v", err)
+		}
+
+		if err := encoder.Encode(chatview.NewFeedbackExport(feedback, prompt.Message, msg, trace)); err != nil {
+			return written, fmt.Errorf("failed to write feedback: %v", err)
+		}
+		written++
+	}
+
+	return written, nil
+}
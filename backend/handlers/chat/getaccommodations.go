@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/accommodations"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/lda"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
@@ -19,65 +20,76 @@ func GetAccomodations(c *gin.Context,
 	chatHistories *[]models.ChatHistory,
 	accoms *[]models.Accommodations,
 ) (*FinalResponse, error) {
-	lambdaClient := lda.GetLambda()
+	agent := lda.GetAgent()
 	db := db.GetDB()
 
 	var user models.Users
 	db.Find(&user, "user_id = ?", trip.UserID)
+	locale := i18n.Locale(c)
 
 	tripString, err := json.Marshal(trip)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal trip: %v", err)
 	}
-	flightString, err := json.Marshal(accoms)
+	// With tools the agent searches accommodation itself, so none are sent.
+	var accomString []byte
+	if !toolsEnabled() {
+		accomString, err = json.Marshal(accoms)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal accommodations: %v", err)
+		}
+	}
+
+	history, err := historyForAgent(trip, *chatHistories)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal accommodations: %v", err)
+		return nil, err
 	}
 
-	payload := LambdaPayload{
+	payload := lda.LambdaPayload{
 		UserPrompt:           chat.Content,
 		FirstName:            user.Username,
-		Today:                time.Now().Format("Monday, January 2, 2006"),
+		Today:                i18n.FormatDate(locale, time.Now()),
 		UserCountry:          user.Nationality,
+		Locale:               locale,
 		ExistingContext:      string(tripString),
-		ChatHistory:          models.ChatHistories(*chatHistories).ToString(),
-		AccommodationOptions: string(flightString),
+		ChatHistory:          history,
+		AccommodationOptions: string(accomString),
+		CheckInDate:          tripDate(trip.StartDate),
+		CheckOutDate:         tripDate(trip.EndDate),
+		Guests:               trip.NumberOfTravelers,
 	}
 
-	request := LambdaRequest{
-		Body: payload,
-	}
-
-	marshaledPayload, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	decide := agentCall(agent.DecideAccommodation)
+	if toolsEnabled() {
+		decide = withTools(*lda.ACCOMMODATION, decide, accommodationTools())
 	}
-
-	output, err := lambdaClient.Invoke(c.Request.Context(), &lambda.InvokeInput{
-		FunctionName: lda.FLIGHT,
-		Payload:      marshaledPayload,
-	})
+	finalResp, err := askForFinalResponse(c.Request.Context(), *lda.ACCOMMODATION, decide, payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke Lambda function: %v", err)
-	}
-
-	// Parse the Lambda response
-	var lambdaResp LambdaResponse
-	if err := json.Unmarshal(output.Payload, &lambdaResp); err != nil {
-		return nil, fmt.Errorf("failed to parse Lambda response: %v", err)
+		return nil, err
 	}
 
-	// Parse the body JSON string
-	var bodyResp BodyResponse
-	if err := json.Unmarshal([]byte(lambdaResp.Body), &bodyResp); err != nil {
-		return nil, fmt.Errorf("failed to parse body: %v", err)
+	// Only keep recommendations for accommodations we actually offered, or
+	// that exist when the agent searched for them, attaching the full record
+	// so the client can render it.
+	offered := make(map[string]models.Accommodations, len(*accoms))
+	for _, accom := range *accoms {
+		offered[accom.AccommodationID] = accom
 	}
-
-	// Parse the inner response JSON string
-	finalResp, err := parseResponse(bodyResp.Response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse inner response: %v", err)
+	var recommendations []models.AccommodationRecommendation
+	for _, rec := range finalResp.AccommodationOptions {
+		accom, ok := offered[rec.AccommodationID]
+		if !ok && toolsEnabled() {
+			if found, err := accommodations.FindAccommodationByID(rec.AccommodationID); err == nil {
+				accom, ok = *found, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		rec.Accommodation = &accom
+		recommendations = append(recommendations, rec)
 	}
+	finalResp.AccommodationOptions = recommendations
 
 	return finalResp, nil
 }
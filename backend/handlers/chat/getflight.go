@@ -1,13 +1,21 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/flights"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/lda"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
@@ -38,19 +46,66 @@ type SelectedFlightWrapper struct {
 	SelectedFlight models.SelectedFlight `json:"selected_flight"`
 }
 
-type ResponseBody struct {
-	SelectedFlight  string  `json:"selected_flight"`
-	TripPreferences *string `json:"trip_preferences"`
-	Mode            string  `json:"mode"`
+// planningModes are the trip paces offered for every conversation.
+var planningModes = []string{"chill", "moderate", "intense"}
+
+// flightPlanningDeadline bounds the whole flight stage across all modes.
+const flightPlanningDeadline = 2 * time.Minute
+
+// defaultFlightCandidates applies when FLIGHT_CANDIDATES is unset.
+const defaultFlightCandidates = 10
+
+const (
+	// defaultFlightCacheTTL applies when FLIGHT_CACHE_TTL is unset.
+	defaultFlightCacheTTL = 30 * time.Minute
+	maxFlightCacheEntries = 1000
+)
+
+// flightDecisions caches validated flight decider answers by the flights on
+// offer, the mode and the trip's shape. Flights are part of the key, so a
+// change to any of them is a miss rather than a stale answer.
+var flightDecisions = lda.NewResponseCache(maxFlightCacheEntries)
+
+// flightCacheTTL is how long a flight decision is reused. FLIGHT_CACHE_TTL
+// takes a duration such as "10m"; "0" disables the cache.
+func flightCacheTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("FLIGHT_CACHE_TTL")); err == nil {
+		return ttl
+	}
+	return defaultFlightCacheTTL
+}
+
+// flightCandidates returns the ranked outbound and return flights offered
+// to the flight decider for a trip, starting from its origin or, without
+// one, the traveller's nationality.
+func flightCandidates(trip *models.Trip) (*flights.Candidates, error) {
+	db := db.GetDB()
+
+	var user models.Users
+	if err := db.Find(&user, "user_id = ?", trip.UserID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	return flights.FindCandidates(trip, user.Nationality, flightCandidateLimit())
+}
+
+// flightCandidateLimit is how many outbound and how many return flights
+// are offered. FLIGHT_CANDIDATES takes a count; "0" offers them all.
+func flightCandidateLimit() int {
+	if limit, err := strconv.Atoi(os.Getenv("FLIGHT_CANDIDATES")); err == nil {
+		return limit
+	}
+	return defaultFlightCandidates
 }
 
 func getFlight(c *gin.Context,
 	trip *models.Trip,
 	chat chatparams.CreateParams,
 	chatHistories *[]models.ChatHistory,
-	flights *[]models.Flights,
+	candidates *flights.Candidates,
+	emit progress,
 ) (*FinalResponse, error) {
-	lambdaClient := lda.GetLambda()
+	agent := lda.GetAgent()
 	db := db.GetDB()
 
 	var user models.Users
@@ -60,90 +115,247 @@ func getFlight(c *gin.Context,
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal trip: %v", err)
 	}
-	flightString, err := json.Marshal(flights)
+	// With tools the agent searches flights itself, so none are sent.
+	// Candidates come ranked with ties broken by flight, so identical flight
+	// sets stay identical to the agent and to the flight decision cache.
+	var flightString []byte
+	if candidates != nil {
+		flightString, err = json.Marshal(candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal flights: %v", err)
+		}
+	}
+	shapeString, err := json.Marshal(tripShape(trip))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal flights: %v", err)
+		return nil, fmt.Errorf("failed to marshal trip: %v", err)
 	}
 
-	modes := []string{"chill", "moderate", "intense"}
-	var results []models.TripPlans
+	locale := i18n.Locale(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), flightPlanningDeadline)
+	defer cancel()
 
-	for _, mode := range modes {
-		payload := LambdaPayload{
-			FlightDetails:   string(flightString),
-			TripPreferences: string(tripString),
-			Mode:            mode,
-		}
+	// Every mode runs concurrently against the same deadline. A failing mode
+	// is reported in ModeErrors instead of discarding the others.
+	plans := make([]*models.TripPlans, len(planningModes))
+	errs := make([]error, len(planningModes))
+	var wg sync.WaitGroup
+	for i, mode := range planningModes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plans[i], errs[i] = planMode(ctx, agent, locale, mode, string(flightString), string(tripString), string(shapeString), emit)
+			if errs[i] != nil {
+				emit(EventModeError, models.ModeError{Mode: mode, Error: errs[i].Error()})
+			}
+		}()
+	}
+	wg.Wait()
 
-		request := LambdaRequest{
-			Body: payload,
+	var results []models.TripPlans
+	var modeErrors []models.ModeError
+	for i, mode := range planningModes {
+		if errs[i] != nil {
+			modeErrors = append(modeErrors, models.ModeError{Mode: mode, Error: errs[i].Error()})
+			continue
 		}
-
-		marshaledPayload, err := json.Marshal(request)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		if err := saveItinerary(trip, plans[i].Itinerary); err != nil {
+			return nil, err
 		}
+		results = append(results, *plans[i])
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("all modes failed: %w", errors.Join(errs...))
+	}
+
+	return &FinalResponse{TripOptions: results, ModeErrors: modeErrors}, nil
+}
+
+// planMode asks the flight decider for the best flight in one mode and then
+// has the planner build that mode's trip around it.
+func planMode(ctx context.Context,
+	agent lda.Agent,
+	locale string,
+	mode string,
+	flightString string,
+	tripString string,
+	shapeString string,
+	emit progress,
+) (*models.TripPlans, error) {
+	emit(EventStage, stageEvent(StageChoosingFlight, i18n.Translate(locale, "progress.choosing_flight", i18n.Translate(locale, "mode."+mode))))
+	payload := lda.LambdaPayload{
+		FlightDetails:   flightString,
+		TripPreferences: tripString,
+		Mode:            mode,
+		Locale:          locale,
+	}
+
+	decide := agentCall(agent.DecideFlight)
+	cacheKey := lda.PayloadKey(*lda.FLIGHT, lda.LambdaPayload{
+		FlightDetails:   flightString,
+		TripPreferences: shapeString,
+		Mode:            mode,
+		Locale:          locale,
+	})
+	// A decision made from searches depends on the catalog at the time, so
+	// it is not cached.
+	if toolsEnabled() {
+		decide = withTools(*lda.FLIGHT, decide, flightTools())
+		cacheKey = ""
+	}
+	respBody, selectedFlightWrapper, err := decideFlight(ctx, decide, payload, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	marshaledFlight, err := json.Marshal(selectedFlightWrapper.SelectedFlight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal selected flight: %v", err)
+	}
+	payload.SelectedFlight = string(marshaledFlight)
+
+	emit(EventStage, stageEvent(StagePlanningTrip, i18n.Translate(locale, "progress.planning_trip", i18n.Translate(locale, "mode."+mode))))
+	itinerary, err := planItinerary(ctx, agent, payload)
+	if err != nil {
+		return nil, err
+	}
+	itinerary.Mode = mode
+
+	tripPlan := models.TripPlans{
+		SelectedFlight:  selectedFlightWrapper.SelectedFlight,
+		TripPreferences: respBody.TripPreferences,
+		Mode:            respBody.Mode,
+		Itinerary:       itinerary,
+	}
+	emit(EventTripPlan, tripPlan)
 
-		output, err := lambdaClient.Invoke(c.Request.Context(), &lambda.InvokeInput{
-			FunctionName: lda.FLIGHT,
-			Payload:      marshaledPayload,
+	return &tripPlan, nil
+}
+
+// planItinerary asks the planner for the day-by-day itinerary of a trip
+// built around the selected flight in payload.
+func planItinerary(ctx context.Context, agent lda.Agent, payload lda.LambdaPayload) (*models.Itinerary, error) {
+	text, err := finalResponseText(ctx, agent.PlanTrip, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var itinerary models.Itinerary
+	err = decodeAgentOutput(ctx, *lda.PLANNER, text, itinerarySchema, &itinerary,
+		func(ctx context.Context, previous string, errs []string) (string, error) {
+			payload.PreviousResponse = previous
+			payload.ValidationErrors = errs
+			return finalResponseText(ctx, agent.PlanTrip, payload)
 		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to invoke Lambda function: %v", err)
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Parse the Lambda response
-		var lambdaResp LambdaResponse
-		if err := json.Unmarshal(output.Payload, &lambdaResp); err != nil {
-			return nil, fmt.Errorf("failed to parse Lambda response: %v", err)
-		}
+	return &itinerary, nil
+}
 
-		var respBody ResponseBody
-		if err := json.Unmarshal([]byte(lambdaResp.Body), &respBody); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response body: %v", err)
-		}
+// saveItinerary stores the itinerary of one mode of a trip, replacing the
+// one planned on an earlier turn.
+func saveItinerary(trip *models.Trip, itinerary *models.Itinerary) error {
+	db := db.GetDB()
 
-		var selectedFlightWrapper SelectedFlightWrapper
-		trimmed := strings.TrimPrefix(respBody.SelectedFlight, "```json")
-		trimmed = strings.TrimSuffix(trimmed, "```")
-		if err := json.Unmarshal([]byte(trimmed), &selectedFlightWrapper); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal selected flight: %v", err)
-		}
-		marshaledFlight, err := json.Marshal(selectedFlightWrapper.SelectedFlight)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal selected flight: %v", err)
+	itinerary.TripID = trip.TripID
+	itinerary.UserID = trip.UserID
+
+	var existing models.Itinerary
+	if err := db.Find(&existing, "trip_id = ? AND mode = ?", trip.TripID, itinerary.Mode).Error; err != nil {
+		return fmt.Errorf("failed to find itinerary: %v", err)
+	}
+
+	if existing.TripID == "" {
+		if err := db.Create(itinerary).Error; err != nil {
+			return fmt.Errorf("failed to create itinerary: %v", err)
 		}
-		payload.SelectedFlight = string(marshaledFlight)
+		return nil
+	}
 
-		// Update marshaledPayload with selected flight
-		request.Body = payload
-		marshaledPayload, err = json.Marshal(request)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal updated payload: %v", err)
+	itinerary.CreatedAt = existing.CreatedAt
+	itinerary.UpdatedAt = models.Now()
+	if err := db.Model(&existing).Updates(map[string]any{
+		"summary":              itinerary.Summary,
+		"days":                 itinerary.Days,
+		"total_estimated_cost": itinerary.TotalEstimatedCost,
+		"currency":             itinerary.Currency,
+		"updated_at":           itinerary.UpdatedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update itinerary: %v", err)
+	}
+
+	return nil
+}
+
+// decideFlight asks the flight decider for the best flight of a payload,
+// answering from the flight decision cache when it has seen the same input.
+// Only answers that validate are cached, and nothing is when cacheKey is
+// empty.
+func decideFlight(ctx context.Context,
+	decide agentCall,
+	payload lda.LambdaPayload,
+	cacheKey string,
+) (*lda.FlightResponse, *SelectedFlightWrapper, error) {
+	var respBody lda.FlightResponse
+	var selectedFlightWrapper SelectedFlightWrapper
+
+	if cached, ok := flightDecisions.Get(cacheKey); cacheKey != "" && ok {
+		if err := json.Unmarshal([]byte(cached.Body), &respBody); err == nil &&
+			len(lda.DecodeOutput(respBody.SelectedFlight, selectedFlightWrapperSchema, &selectedFlightWrapper)) == 0 {
+			log.Printf("Using cached %s answer for %s mode", *lda.FLIGHT, payload.Mode)
+			return &respBody, &selectedFlightWrapper, nil
 		}
+	}
+
+	respBody = lda.FlightResponse{}
+	lambdaResp, err := decide(ctx, payload)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		planOutput, err := lambdaClient.Invoke(c.Request.Context(), &lambda.InvokeInput{
-			FunctionName: lda.PLANNER,
-			Payload:      marshaledPayload,
+	if err := json.Unmarshal([]byte(lambdaResp.Body), &respBody); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal response body: %v", err)
+	}
+
+	err = decodeAgentOutput(ctx, *lda.FLIGHT, respBody.SelectedFlight, selectedFlightWrapperSchema, &selectedFlightWrapper,
+		func(ctx context.Context, previous string, errs []string) (string, error) {
+			retry := payload
+			retry.PreviousResponse = previous
+			retry.ValidationErrors = errs
+			lambdaResp, err = decide(ctx, retry)
+			if err != nil {
+				return "", err
+			}
+			if err := json.Unmarshal([]byte(lambdaResp.Body), &respBody); err != nil {
+				return "", fmt.Errorf("failed to unmarshal response body: %v", err)
+			}
+			return respBody.SelectedFlight, nil
 		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to invoke Lambda function: %v", err)
-		}
+	if err != nil {
+		return nil, nil, err
+	}
 
-		// Parse the Lambda response
-		var planRes LambdaResponse
-		if err := json.Unmarshal(planOutput.Payload, &planRes); err != nil {
-			return nil, fmt.Errorf("failed to parse Lambda response: %v", err)
-		}
+	if cacheKey != "" {
+		flightDecisions.Put(cacheKey, lambdaResp, flightCacheTTL())
+	}
+	return &respBody, &selectedFlightWrapper, nil
+}
 
-		tripPlan := models.TripPlans{
-			SelectedFlight:  selectedFlightWrapper.SelectedFlight,
-			TripPreferences: respBody.TripPreferences,
-			Mode:            respBody.Mode,
+// tripShape is the part of a trip a flight decision depends on. Fields that
+// identify the conversation are left out so that different users planning
+// the same trip share cached decisions.
+func tripShape(trip *models.Trip) map[string]any {
+	shape := map[string]any{}
+	v := reflect.ValueOf(trip).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if systemTripFields[field.Name] || field.Name == "TripName" {
+			continue
 		}
-		results = append(results, tripPlan)
+		shape[jsonFieldName(field)] = v.Field(i).Interface()
 	}
-
-	fmt.Printf("Final Results: %+v\n", results)
-	return &FinalResponse{TripOptions: results}, nil
+	shape["start_date"] = tripDate(trip.StartDate)
+	shape["end_date"] = tripDate(trip.EndDate)
+	return shape
 }
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// guardMessage runs the guardrails over a user message before it is stored,
+// replacing its content with the sanitized text, and returns the message to
+// store with the decision logged on it.
+func guardMessage(body *chatparams.CreateParams, userID string, object any) *models.ChatHistory {
+	decision := guardrail.Check(body.Content)
+	body.Content = decision.Text
+
+	model := body.ToModel(userID, object)
+	model.Guardrail = decision.Action
+	model.GuardrailRules = decision.Rules
+
+	if decision.Action != guardrail.ActionPass {
+		log.Printf("Guardrail %s message %s of conversation %s: %s",
+			decision.Action, model.ChatID, body.ChatHistoryID, strings.Join(decision.Rules, ", "))
+	}
+	return model
+}
+
+// blockedTurn answers a blocked user message, the last on the active branch,
+// with a canned reply instead of asking the agent.
+func blockedTurn(c *gin.Context, trip *models.Trip, blocked *models.ChatHistory) (*chatview.ChatResponse, *chatError) {
+	if _, err := resolveStage(trip); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_resolve_stage"), err)
+	}
+
+	locale := i18n.Locale(c)
+	message := i18n.Translate(locale, "chat.message_blocked")
+	if slices.Contains(blocked.GuardrailRules, guardrail.RuleMaxLength) {
+		message = i18n.Translate(locale, "chat.message_too_long", guardrail.MaxLength())
+	}
+
+	resMsg := models.ChatHistory{
+		ChatHistoryID: trip.TripID,
+		ChatID:        uuid.New().String(),
+		UserID:        trip.UserID,
+		UserOrAgent:   "agent",
+		Message:       message,
+		Guardrail:     guardrail.ActionBlock,
+		Timestamp:     models.Now(),
+	}
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
+	}
+
+	return &chatview.ChatResponse{
+		ConversationID: trip.TripID,
+		Content:        resMsg.Message,
+		Stage:          trip.Stage,
+		Blocked:        true,
+		CreatedAt:      resMsg.Timestamp.ToString(),
+		IsUser:         false,
+	}, nil
+}
+
+// withoutBlocked drops blocked user messages and the canned replies to them,
+// which are never shown to the agents.
+func withoutBlocked(chatHistories []models.ChatHistory) []models.ChatHistory {
+	return slices.DeleteFunc(slices.Clone(chatHistories), func(msg models.ChatHistory) bool {
+		return msg.Guardrail == guardrail.ActionBlock
+	})
+}
@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+const (
+	// defaultHistoryTokenBudget applies when CHAT_HISTORY_TOKEN_BUDGET is unset.
+	defaultHistoryTokenBudget = 2000
+	// objectElisionLength is the size above which a stored flight,
+	// accommodation or requirement object is replaced by a placeholder.
+	objectElisionLength = 300
+)
+
+// historyTokenBudget is the approximate number of tokens of chat history
+// sent with each agent call.
+func historyTokenBudget() int {
+	if budget, err := strconv.Atoi(os.Getenv("CHAT_HISTORY_TOKEN_BUDGET")); err == nil && budget > 0 {
+		return budget
+	}
+	return defaultHistoryTokenBudget
+}
+
+// estimateTokens approximates a token count at four characters per token.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// historyForAgent builds the chat_history sent to the agent within the token
+// budget. The newest turns are kept verbatim apart from large objects, and
+// the turns before them are folded into the conversation's rolling summary,
+// a digest of what they settled and what the user said, which is sent first
+// as a message from "summary". A quarter of the budget is left for it. Blocked messages and the
+// replies to them are left out.
+func historyForAgent(trip *models.Trip, chatHistories []models.ChatHistory) (string, error) {
+	chatHistories = withoutBlocked(chatHistories)
+	budget := historyTokenBudget()
+	summaryBudget := budget / 4
+	recentBudget := budget - summaryBudget
+
+	// Walk back from the newest turn, always keeping at least that one.
+	start := len(chatHistories)
+	used := 0
+	for start > 0 {
+		data, _ := json.Marshal(elideObjects(chatHistories[start-1]))
+		cost := estimateTokens(string(data))
+		if used+cost > recentBudget && start < len(chatHistories) {
+			break
+		}
+		used += cost
+		start--
+	}
+
+	history := make(models.ChatHistories, 0, len(chatHistories)-start+1)
+	if start > 0 {
+		summary, err := updateSummary(trip, chatHistories, start, summaryBudget)
+		if err != nil {
+			return "", err
+		}
+		history = append(history, models.ChatHistory{
+			ChatHistoryID: trip.TripID,
+			UserOrAgent:   "summary",
+			Message:       summary,
+		})
+	}
+	for _, msg := range chatHistories[start:] {
+		history = append(history, elideObjects(msg))
+	}
+
+	return history.ToString(), nil
+}
+
+// updateSummary makes sure the stored summary covers every message before
+// chatHistories[end], folding the turns it is missing into its digest, and
+// holds the requirements the trip has now within budget tokens.
+func updateSummary(trip *models.Trip, chatHistories []models.ChatHistory, end int, budget int) (string, error) {
+	db := db.GetDB()
+
+	var summary models.ConversationSummary
+	if err := db.Find(&summary, "conversation_id = ?", trip.TripID).Error; err != nil {
+		return "", fmt.Errorf("failed to find conversation summary: %v", err)
+	}
+	isNew := summary.ConversationID == ""
+
+	// Resume after the last summarized message. If it is no longer part of
+	// the history, or the summary is not a digest, it is rebuilt from
+	// scratch.
+	facts, ok := parseSummary(summary.Summary)
+	from := 0
+	if summary.SummarizedThrough != "" && ok {
+		from = -1
+		for i, msg := range chatHistories {
+			if msg.ChatID == summary.SummarizedThrough {
+				from = i + 1
+				break
+			}
+		}
+	}
+	if from < 0 || !ok {
+		from = 0
+		facts = nil
+	}
+	for _, msg := range chatHistories[from:end] {
+		facts = facts.add(msg)
+	}
+	facts = facts.addTrip(*trip).fit(budget)
+
+	digest := facts.String()
+	if !isNew && digest == summary.Summary && summary.SummarizedThrough == chatHistories[end-1].ChatID {
+		return digest, nil
+	}
+	summary.Summary = digest
+	summary.SummarizedThrough = chatHistories[end-1].ChatID
+
+	if isNew {
+		summary.ConversationID = trip.TripID
+		if err := db.Create(&summary).Error; err != nil {
+			return "", fmt.Errorf("failed to create conversation summary: %v", err)
+		}
+	} else if err := db.Model(&summary).Updates(map[string]any{
+		"summary":            summary.Summary,
+		"summarized_through": summary.SummarizedThrough,
+		"updated_at":         models.Now(),
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to update conversation summary: %v", err)
+	}
+
+	return summary.Summary, nil
+}
+
+// elideObjects replaces stored objects too large to resend with a note of
+// their size.
+func elideObjects(msg models.ChatHistory) models.ChatHistory {
+	elide := func(object string) string {
+		if len(object) <= objectElisionLength {
+			return object
+		}
+		return fmt.Sprintf("[elided %d characters]", len(object))
+	}
+	msg.ReqObject = elide(msg.ReqObject)
+	msg.FlightObject = elide(msg.FlightObject)
+	msg.AccommodationObject = elide(msg.AccommodationObject)
+	return msg
+}
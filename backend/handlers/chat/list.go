@@ -0,0 +1,136 @@
+package chat
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// lastMessagePreviewLength caps the last message shown for each conversation.
+const lastMessagePreviewLength = 100
+
+// ListHandler returns the caller's conversations, most recently active
+// first. The cursor is the conversation ID of the last entry of the
+// previous page.
+func ListHandler(c *gin.Context) {
+	var params chatparams.PageParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_query_parameters") + ": " + err.Error()})
+		return
+	}
+	if err := params.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.validation_failed") + ": " + err.Error()})
+		return
+	}
+	limit, _ := params.GetLimitInt()
+
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	db := db.GetDB()
+	var trips []models.Trip
+	if err := db.Where("user_id = ?", userID).Find(&trips).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_trips") + ": " + err.Error()})
+		return
+	}
+
+	for i := range trips {
+		trip := &trips[i]
+		if _, err := resolveStage(trip); err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_resolve_stage") + ": " + err.Error()})
+			return
+		}
+		if err := resolveActivity(trip); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	sort.SliceStable(trips, func(i, j int) bool {
+		return lastActivity(&trips[i]).After(lastActivity(&trips[j]))
+	})
+
+	conversations := make([]chatview.ConversationView, 0, len(trips))
+	for _, trip := range trips {
+		var lastActivityAt string
+		if activity := lastActivity(&trip); !activity.IsZero() {
+			lastActivityAt = activity.Format(time.RFC3339)
+		}
+		conversations = append(conversations, chatview.ConversationView{
+			ConversationID:     trip.TripID,
+			TripName:           trip.TripName,
+			DestinationCountry: trip.DestinationCountry,
+			DestinationCities:  trip.DestinationCities,
+			StartDate:          trip.StartDate,
+			EndDate:            trip.EndDate,
+			Stage:              trip.Stage,
+			LastMessage:        trip.LastMessagePreview,
+			LastActivityAt:     lastActivityAt,
+		})
+	}
+
+	start := 0
+	if params.Cursor != nil && *params.Cursor != "" {
+		start = -1
+		for i, conversation := range conversations {
+			if conversation.ConversationID == *params.Cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_cursor")})
+			return
+		}
+	}
+
+	end := min(start+limit, len(conversations))
+	res := chatview.ConversationsResponse{
+		Conversations: conversations[start:end],
+	}
+	if end < len(conversations) {
+		res.NextCursor = conversations[end-1].ConversationID
+	}
+
+	c.JSON(200, res)
+}
+
+// lastActivity is when a conversation last had a message, or when it was
+// created if it has none. Conversations created before either was recorded
+// have neither and sort last.
+func lastActivity(trip *models.Trip) time.Time {
+	if !trip.LastActivityAt.IsZero() {
+		return time.Time(trip.LastActivityAt)
+	}
+	return time.Time(trip.CreatedAt)
+}
+
+// resolveActivity records the latest message of a conversation created
+// before activity was stored on the trip, once it has one. Newer trips keep
+// theirs up to date as messages are added.
+func resolveActivity(trip *models.Trip) error {
+	if !trip.LastActivityAt.IsZero() || !trip.CreatedAt.IsZero() {
+		return nil
+	}
+	chatHistories, err := loadChatHistories(trip)
+	if err != nil || len(chatHistories) == 0 {
+		return err
+	}
+	return setActiveLeaf(trip, chatHistories[len(chatHistories)-1])
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
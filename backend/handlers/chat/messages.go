@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// MessagesHandler returns a conversation's messages oldest first, a page at
+// a time. The cursor is the chat ID of the last message of the previous page.
+func MessagesHandler(c *gin.Context) {
+	var params chatparams.PageParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_query_parameters") + ": " + err.Error()})
+		return
+	}
+	if err := params.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.validation_failed") + ": " + err.Error()})
+		return
+	}
+	limit, _ := params.GetLimitInt()
+
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	chatHistories, err := loadChatHistories(trip)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	start := 0
+	if params.Cursor != nil && *params.Cursor != "" {
+		start = -1
+		for i, msg := range chatHistories {
+			if msg.ChatID == *params.Cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_cursor")})
+			return
+		}
+	}
+
+	end := min(start+limit, len(chatHistories))
+	page := chatHistories[start:end]
+
+	res := chatview.MessagesResponse{
+		ConversationID: trip.TripID,
+		Messages:       make([]chatview.MessageView, 0, len(page)),
+	}
+	for _, msg := range page {
+		res.Messages = append(res.Messages, chatview.NewMessageView(msg))
+	}
+	if end < len(chatHistories) {
+		res.NextCursor = page[len(page)-1].ChatID
+	}
+
+	c.JSON(200, res)
+}
+
+// loadChatHistories returns the messages on a conversation's active branch,
+// oldest first.
+func loadChatHistories(trip *models.Trip) ([]models.ChatHistory, error) {
+	db := db.GetDB()
+
+	var chatHistories []models.ChatHistory
+	if err := db.Where("chat_history_id = ?", trip.TripID).Find(&chatHistories).Error; err != nil {
+		return nil, fmt.Errorf("failed to find chat histories: %v", err)
+	}
+	// Messages are keyed by chat ID, so the table does not order them. A
+	// message and its reply can share a second; the branch is ordered by
+	// parent links, which the timestamp order only has to respect for
+	// conversations written before branching.
+	sort.SliceStable(chatHistories, func(i, j int) bool {
+		return time.Time(chatHistories[i].Timestamp).Before(time.Time(chatHistories[j].Timestamp))
+	})
+
+	if err := resolveBranch(trip, chatHistories); err != nil {
+		return nil, err
+	}
+
+	return activeBranch(chatHistories, trip.ActiveLeafID), nil
+}
@@ -0,0 +1,108 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yihao03/Aistronaut/m/v2/lda"
+)
+
+// repairFunc asks the agent again, showing it its previous answer and what
+// was wrong with it, and returns the new answer text.
+type repairFunc func(ctx context.Context, previous string, errs []string) (string, error)
+
+// decodeAgentOutput decodes an agent's answer into target. An answer that
+// does not match schema gets one repair round-trip; if that fails too an
+// *lda.OutputError is returned.
+func decodeAgentOutput(ctx context.Context,
+	function string,
+	text string,
+	schema *lda.Schema,
+	target any,
+	repair repairFunc,
+) error {
+	errs := lda.DecodeOutput(text, schema, target)
+	if len(errs) == 0 {
+		return nil
+	}
+	log.Printf("Invalid output from %s, requesting repair: %v", function, errs)
+
+	repaired, err := repair(ctx, text, errs)
+	if err != nil {
+		return err
+	}
+
+	if errs = lda.DecodeOutput(repaired, schema, target); len(errs) > 0 {
+		return &lda.OutputError{Function: function, Errors: errs}
+	}
+	return nil
+}
+
+type agentCall func(ctx context.Context, payload lda.LambdaPayload) (*lda.LambdaResponse, error)
+
+// askForFinalResponse invokes an agent function whose body carries a
+// FinalResponse in its "response" text.
+func askForFinalResponse(ctx context.Context, function string, call agentCall, payload lda.LambdaPayload) (*FinalResponse, error) {
+	text, err := finalResponseText(ctx, call, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var finalResp FinalResponse
+	err = decodeAgentOutput(ctx, function, text, finalResponseSchema, &finalResp,
+		func(ctx context.Context, previous string, errs []string) (string, error) {
+			payload.PreviousResponse = previous
+			payload.ValidationErrors = errs
+			return finalResponseText(ctx, call, payload)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &finalResp, nil
+}
+
+func finalResponseText(ctx context.Context, call agentCall, payload lda.LambdaPayload) (string, error) {
+	lambdaResp, err := call(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+
+	// Parse the body JSON string
+	var bodyResp lda.TextResponse
+	if err := json.Unmarshal([]byte(lambdaResp.Body), &bodyResp); err != nil {
+		return "", fmt.Errorf("failed to parse body: %v", err)
+	}
+
+	return bodyResp.Response, nil
+}
+
+// agentErrorStatus maps an error from an agent stage to an HTTP status:
+// 504 when the agent took too long, 503 when it is throttled or its circuit
+// is open, 502 when it failed, answered with something unusable or kept
+// calling tools.
+func agentErrorStatus(err error) int {
+	var timeoutErr *lda.TimeoutError
+	var throttledErr *lda.ThrottledError
+	var circuitErr *lda.CircuitOpenError
+	var functionErr *lda.FunctionError
+	var payloadErr *lda.PayloadError
+	var outputErr *lda.OutputError
+	var toolLimitErr *lda.ToolLimitError
+	var contractErr *lda.ContractError
+	switch {
+	case errors.As(err, &timeoutErr), errors.Is(err, context.DeadlineExceeded):
+		return 504
+	case errors.As(err, &throttledErr), errors.As(err, &circuitErr):
+		return 503
+	case errors.As(err, &functionErr), errors.As(err, &payloadErr), errors.As(err, &outputErr),
+		errors.As(err, &toolLimitErr):
+		return 502
+	case errors.As(err, &contractErr) && contractErr.Response:
+		return 502
+	}
+	return 500
+}
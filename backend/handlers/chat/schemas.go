@@ -0,0 +1,96 @@
+package chat
+
+import "github.com/yihao03/Aistronaut/m/v2/lda"
+
+var (
+	str     = lda.Of("string")
+	number  = lda.Of("number")
+	integer = lda.Of("integer")
+	// StringArray fields accept a comma separated string or a list.
+	stringList = lda.Of("string", "array")
+)
+
+var tripDetailsSchema = lda.Object(nil, map[string]*lda.Schema{
+	"trip_name":            str,
+	"origin_city":          str,
+	"destination_country":  stringList,
+	"destination_cities":   stringList,
+	"landmarks":            stringList,
+	"start_date":           str,
+	"end_date":             str,
+	"total_budget":         integer,
+	"number_of_travelers":  integer,
+	"adults_count":         integer,
+	"children_count":       integer,
+	"infants_count":        integer,
+	"trip_type":            str,
+	"purpose":              str,
+	"notes":                str,
+	"dietary_restrictions": str,
+})
+
+var flightSchema = lda.Object(nil, map[string]*lda.Schema{
+	"flight_number":  str,
+	"departure_city": str,
+	"arrival_city":   str,
+	"departure_date": str,
+	"departure_time": str,
+	"arrival_date":   str,
+	"arrival_time":   str,
+	"duration_hours": number,
+	"stops":          lda.Array(str),
+})
+
+var selectedFlightSchema = lda.Object([]string{"airline", "outbound_flight", "price"}, map[string]*lda.Schema{
+	"airline":         str,
+	"outbound_flight": flightSchema,
+	"return_flight":   flightSchema,
+	"price":           number,
+	"currency":        str,
+	"reason":          str,
+})
+
+// finalResponseSchema describes FinalResponse as answered by the parser and
+// the accommodation decider.
+var finalResponseSchema = lda.Object([]string{"response"}, map[string]*lda.Schema{
+	"response":     str,
+	"trip_details": tripDetailsSchema,
+	"trip_options": lda.Array(lda.Object([]string{"selected_flight", "mode"}, map[string]*lda.Schema{
+		"selected_flight":  selectedFlightSchema,
+		"trip_preferences": str,
+		"mode":             str,
+	})),
+	"accomodation_details": lda.Of("object"),
+	"accommodation_options": lda.Array(lda.Object([]string{"accommodation_id"}, map[string]*lda.Schema{
+		"accommodation_id": str,
+		"reason":           str,
+	})),
+})
+
+// selectedFlightWrapperSchema describes SelectedFlightWrapper as answered by
+// the flight decider.
+var selectedFlightWrapperSchema = lda.Object([]string{"selected_flight"}, map[string]*lda.Schema{
+	"selected_flight": selectedFlightSchema,
+})
+
+var timeBlockSchema = lda.Object([]string{"activity"}, map[string]*lda.Schema{
+	"start_time":     str,
+	"end_time":       str,
+	"activity":       str,
+	"description":    str,
+	"location":       str,
+	"estimated_cost": number,
+})
+
+// itinerarySchema describes models.Itinerary as answered by the planner.
+var itinerarySchema = lda.Object([]string{"days"}, map[string]*lda.Schema{
+	"summary": str,
+	"days": lda.Array(lda.Object([]string{"day", "blocks"}, map[string]*lda.Schema{
+		"day":    integer,
+		"date":   str,
+		"title":  str,
+		"blocks": lda.Array(timeBlockSchema),
+	})),
+	"total_estimated_cost": number,
+	"currency":             str,
+})
@@ -2,13 +2,13 @@ package chat
 
 import (
 	"encoding/json"
-	"fmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/accommodations"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
-	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 	"github.com/yihao03/Aistronaut/m/v2/params/accommodationparams"
 	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
 )
@@ -22,63 +22,92 @@ func SelectAccommodationHandler(c *gin.Context) {
 	}
 
 	db := db.GetDB()
-	claims, err := myjwt.ParseJWTFromContext(c)
-	if err != nil {
-		c.JSON(403, gin.H{"error": "Unauthorized: " + err.Error()})
+	userID, ok := authorizedUserID(c)
+	if !ok {
 		return
 	}
-	userID, ok := claims["user_id"].(string)
+
+	trip, ok := findOwnedTrip(c, body.ConversationID, userID)
 	if !ok {
-		c.JSON(400, gin.H{"error": "Invalid user ID in token"})
+		return
+	}
+	if err := models.ValidateStageTransition(trip.Stage, models.StageReviewing); err != nil {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.cannot_select_an_accommodation_now") + ": " + err.Error()})
 		return
 	}
 
 	var accommodation models.Accommodations
 	if err := db.Find(&accommodation, "accommodation_id = ?", body.AccommodationID).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find accommodation: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_accommodation") + ": " + err.Error()})
+		return
+	}
+	if accommodation.AccommodationID == "" {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.accommodation_not_found")})
+		return
+	}
+
+	resMsg, chatErr := bookAccommodation(c, trip, accommodation)
+	if chatErr != nil {
+		c.JSON(chatErr.Status, gin.H{"error": chatErr.Message})
 		return
 	}
 
+	resView := chatview.ChatResponse{
+		ConversationID: body.ConversationID,
+		Content:        resMsg.Message,
+		Stage:          trip.Stage,
+		CreatedAt:      resMsg.Timestamp.ToString(),
+		IsUser:         false,
+	}
+
+	c.JSON(200, resView)
+}
+
+// bookAccommodation books an accommodation for a trip, moves it on to review
+// and records the booking in the conversation.
+func bookAccommodation(c *gin.Context, trip *models.Trip, accommodation models.Accommodations) (*models.ChatHistory, *chatError) {
+	db := db.GetDB()
+
+	checkIn, checkOut, err := accommodations.StayDates(trip)
+	if err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_accommodation_booking"), err)
+	}
 	booking := models.AccommodationBookings{
-		UserID:          userID,
-		TripID:          body.ConversationID,
-		AccommodationID: body.AccommodationID,
+		UserID:          trip.UserID,
+		TripID:          trip.TripID,
+		AccommodationID: accommodation.AccommodationID,
 		BookingID:       uuid.New().String(),
+		CheckInDate:     checkIn.Format("2006-01-02"),
+		CheckOutDate:    checkOut.Format("2006-01-02"),
+		NumberOfRooms:   accommodations.RoomsNeeded(accommodation, trip),
 	}
 
 	if err := db.Create(&booking).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create accommodation booking: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_accommodation_booking"), err)
+	}
+
+	if err := setStage(trip, models.StageReviewing); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_update_stage"), err)
 	}
 
-	currTime := models.Now()
 	accommodationJSON, err := json.Marshal(accommodation)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal response: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
 	}
 
 	resMsg := models.ChatHistory{
-		ChatHistoryID:       body.ConversationID,
+		ChatHistoryID:       trip.TripID,
 		ChatID:              uuid.New().String(),
-		UserID:              userID,
+		UserID:              trip.UserID,
 		UserOrAgent:         "agent",
-		Message:             fmt.Sprintf("Accommodation %s selected. Booking confirmed.", accommodation.Name),
+		Message:             i18n.T(c, "chat.accommodation_selected", accommodation.Name),
 		AccommodationObject: string(accommodationJSON),
-		Timestamp:           currTime,
+		Timestamp:           models.Now(),
 	}
 
-	if err := db.Create(&resMsg).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create chat response: " + err.Error()})
-		return
-	}
-
-	resView := chatview.ChatResponse{
-		ConversationID: body.ConversationID,
-		Content:        resMsg.Message,
-		CreatedAt:      currTime.ToString(),
-		IsUser:         false,
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
 	}
 
-	c.JSON(200, resView)
+	return &resMsg, nil
 }
@@ -2,13 +2,13 @@ package chat
 
 import (
 	"encoding/json"
-	"fmt"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
-	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 	"github.com/yihao03/Aistronaut/m/v2/params/flightsparams"
 	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
 )
@@ -22,63 +22,90 @@ func SelectFlightHandler(c *gin.Context) {
 	}
 
 	db := db.GetDB()
-	claims, err := myjwt.ParseJWTFromContext(c)
-	if err != nil {
-		c.JSON(403, gin.H{"error": "Unauthorized: " + err.Error()})
+	userID, ok := authorizedUserID(c)
+	if !ok {
 		return
 	}
-	userID, ok := claims["user_id"].(string)
+
+	trip, ok := findOwnedTrip(c, body.ConversationID, userID)
 	if !ok {
-		c.JSON(400, gin.H{"error": "Invalid user ID in token"})
+		return
+	}
+	if err := models.ValidateStageTransition(trip.Stage, models.StageChoosingAccommodation); err != nil {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.cannot_select_a_flight_now") + ": " + err.Error()})
 		return
 	}
 
 	var flight models.Flights
 	if err := db.Find(&flight, "flight_id = ?", body.FlightID).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find flight: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_flight") + ": " + err.Error()})
 		return
 	}
-
-	booking := models.FlightBookings{
-		UserID:    userID,
-		TripID:    body.ConversationID,
-		FlightID:  body.FlightID,
-		BookingID: uuid.New().String(),
+	if flight.FlightID == "" {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.flight_not_found")})
+		return
 	}
 
-	if err := db.Create(&booking).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create flight booking: " + err.Error()})
+	resMsg, chatErr := bookFlight(c, trip, flight)
+	if chatErr != nil {
+		c.JSON(chatErr.Status, gin.H{"error": chatErr.Message})
 		return
 	}
 
-	currTime := models.Now()
-	flightJSON, err := json.Marshal(flight)
+	resView := chatview.ChatResponse{
+		ConversationID: body.ConversationID,
+		Content:        resMsg.Message,
+		Stage:          trip.Stage,
+		CreatedAt:      resMsg.Timestamp.ToString(),
+		IsUser:         false,
+	}
+
+	c.JSON(200, resView)
+}
+
+// bookFlight books the legs of a trip's flight, the outbound flight first,
+// moves the trip on to choosing accommodation and records the booking in the
+// conversation.
+func bookFlight(c *gin.Context, trip *models.Trip, legs ...models.Flights) (*models.ChatHistory, *chatError) {
+	db := db.GetDB()
+
+	numbers := make([]string, 0, len(legs))
+	for _, flight := range legs {
+		booking := models.FlightBookings{
+			UserID:    trip.UserID,
+			TripID:    trip.TripID,
+			FlightID:  flight.FlightID,
+			BookingID: uuid.New().String(),
+		}
+
+		if err := db.Create(&booking).Error; err != nil {
+			return nil, newChatError(500, i18n.T(c, "error.failed_to_create_flight_booking"), err)
+		}
+		numbers = append(numbers, flight.FlightNumber)
+	}
+
+	if err := setStage(trip, models.StageChoosingAccommodation); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_update_stage"), err)
+	}
+
+	flightJSON, err := json.Marshal(legs[0])
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to marshal response: " + err.Error()})
-		return
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_marshal_response"), err)
 	}
 
 	resMsg := models.ChatHistory{
-		ChatHistoryID: body.ConversationID,
+		ChatHistoryID: trip.TripID,
 		ChatID:        uuid.New().String(),
-		UserID:        userID,
+		UserID:        trip.UserID,
 		UserOrAgent:   "agent",
-		Message:       fmt.Sprintf("Flight %s selected. Let's proceed with accomodations booking", flight.FlightNumber),
+		Message:       i18n.T(c, "chat.flight_selected", strings.Join(numbers, ", ")),
 		FlightObject:  string(flightJSON),
-		Timestamp:     currTime,
+		Timestamp:     models.Now(),
 	}
 
-	if err := db.Create(&resMsg).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create chat response: " + err.Error()})
-		return
-	}
-
-	resView := chatview.ChatResponse{
-		ConversationID: body.ConversationID,
-		Content:        resMsg.Message,
-		CreatedAt:      currTime.ToString(),
-		IsUser:         false,
+	if err := appendMessage(trip, &resMsg); err != nil {
+		return nil, newChatError(500, i18n.T(c, "error.failed_to_create_chat_response"), err)
 	}
 
-	c.JSON(200, resView)
+	return &resMsg, nil
 }
@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// StageBackHandler moves a conversation back to its previous stage, e.g. to
+// pick a different flight after reviewing the trip.
+func StageBackHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	prev, ok := models.PreviousStage(trip.Stage)
+	if !ok {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.cannot_move_back", i18n.T(c, "stage."+trip.Stage))})
+		return
+	}
+
+	if err := setStage(trip, prev); err != nil {
+		c.JSON(409, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, chatview.StageResponse{
+		ConversationID: trip.TripID,
+		Stage:          trip.Stage,
+	})
+}
+
+// ConfirmHandler confirms a trip that is being reviewed.
+func ConfirmHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	if trip.Stage != models.StageReviewing {
+		c.JSON(409, gin.H{"error": i18n.T(c, "error.trip_not_under_review")})
+		return
+	}
+
+	if err := setStage(trip, models.StageConfirmed); err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_update_stage") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, chatview.StageResponse{
+		ConversationID: trip.TripID,
+		Stage:          trip.Stage,
+	})
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/idempotency"
+	"github.com/yihao03/Aistronaut/m/v2/params/chatparams"
+)
+
+// Server-sent event names emitted by ChatStreamHandler.
+const (
+	EventStage     = "stage"
+	EventTripPlan  = "trip_plan"
+	EventModeError = "mode_error"
+	EventResponse  = "response"
+	EventError     = "error"
+)
+
+// Stages reported in EventStage payloads.
+const (
+	StageParsingRequirements = "parsing_requirements"
+	StageSearchingFlights    = "searching_flights"
+	StageChoosingFlight      = "choosing_flight"
+	StagePlanningTrip        = "planning_trip"
+	StageChoosingStay        = "choosing_accommodation"
+)
+
+// progress receives events while a chat turn is being processed. It may be
+// called from several goroutines.
+type progress func(event string, data any)
+
+func noProgress(string, any) {}
+
+func stageEvent(stage, message string) gin.H {
+	return gin.H{"stage": stage, "message": message}
+}
+
+type chatError struct {
+	Status  int
+	Message string
+}
+
+func (e *chatError) Error() string {
+	return e.Message
+}
+
+func newChatError(status int, message string, err error) *chatError {
+	if err != nil {
+		message += ": " + err.Error()
+	}
+	return &chatError{Status: status, Message: message}
+}
+
+// ChatStreamHandler is the server-sent events variant of ChatHandler. It
+// streams a "stage" event as each stage starts, a "trip_plan" or
+// "mode_error" event for each mode as soon as it finishes, and finally the
+// ChatResponse as a "response" event, or an "error" event if the turn fails.
+func ChatStreamHandler(c *gin.Context) {
+	var body chatparams.CreateParams
+
+	if err := c.Bind(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	trip, ok := authorizeTurn(c, &body)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	var mu sync.Mutex
+	emit := func(event string, data any) {
+		mu.Lock()
+		defer mu.Unlock()
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+
+	resView, chatErr := processChat(c, trip, body, emit)
+	if chatErr != nil {
+		// The stream has already answered 200, so the failed turn is kept
+		// from being replayed to a retry.
+		idempotency.Discard(c)
+		emit(EventError, gin.H{"error": chatErr.Message, "status": chatErr.Status})
+		return
+	}
+
+	emit(EventResponse, resView)
+}
@@ -0,0 +1,222 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// A conversation's summary is a digest of its earlier turns: the
+// requirements captured, the options offered, what was booked and what the
+// user said in their own words. It is kept as one "key: value" line per
+// fact, and a later turn replaces the facts it updates, so the summary grows
+// with the trip rather than with the conversation. What the user said is
+// kept a line per message instead, and the oldest lines are dropped once the
+// digest outgrows its budget.
+
+// Keys of the facts that are not trip fields.
+const (
+	factStage                 = "stage"
+	factFlightsOffered        = "flights offered"
+	factFlightBooked          = "flight booked"
+	factAccommodationsOffered = "accommodations offered"
+	factAccommodationBooked   = "accommodation booked"
+	factUserSaid              = "user said"
+)
+
+// summaryValueLength caps the value of each fact.
+const summaryValueLength = 200
+
+type summaryFact struct {
+	key   string
+	value string
+}
+
+type summaryFacts []summaryFact
+
+// summaryKeys are the keys a digest can hold: the trip's requirement fields
+// by their JSON names, and the decisions.
+var summaryKeys = func() map[string]bool {
+	keys := map[string]bool{
+		factStage:                 true,
+		factFlightsOffered:        true,
+		factFlightBooked:          true,
+		factAccommodationsOffered: true,
+		factAccommodationBooked:   true,
+		factUserSaid:              true,
+	}
+	tripType := reflect.TypeOf(models.Trip{})
+	for i := 0; i < tripType.NumField(); i++ {
+		if key, ok := requirementKey(tripType.Field(i)); ok {
+			keys[key] = true
+		}
+	}
+	return keys
+}()
+
+// requirementKey returns the JSON name of a trip field the user gives.
+func requirementKey(field reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" || systemTripFields[field.Name] {
+		return "", false
+	}
+	return name, true
+}
+
+// parseSummary reads a stored digest. It reports false for a summary in any
+// other form, such as the transcripts stored before digests, which is then
+// rebuilt.
+func parseSummary(summary string) (summaryFacts, bool) {
+	var facts summaryFacts
+	if summary == "" {
+		return facts, true
+	}
+	for _, line := range strings.Split(summary, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || !summaryKeys[key] {
+			return nil, false
+		}
+		facts = append(facts, summaryFact{key, value})
+	}
+	return facts, true
+}
+
+func (f summaryFacts) String() string {
+	lines := make([]string, len(f))
+	for i, fact := range f {
+		lines[i] = fact.key + ": " + fact.value
+	}
+	return strings.Join(lines, "\n")
+}
+
+// set replaces the fact with the same key, or adds it.
+func (f summaryFacts) set(key, value string) summaryFacts {
+	value = truncate(strings.Join(strings.Fields(value), " "), summaryValueLength)
+	for i := range f {
+		if f[i].key == key {
+			f[i].value = value
+			return f
+		}
+	}
+	return append(f, summaryFact{key, value})
+}
+
+// unset removes the fact with the given key.
+func (f summaryFacts) unset(key string) summaryFacts {
+	for i := range f {
+		if f[i].key == key {
+			return append(f[:i], f[i+1:]...)
+		}
+	}
+	return f
+}
+
+// note adds a line with something the user said.
+func (f summaryFacts) note(message string) summaryFacts {
+	message = truncate(strings.Join(strings.Fields(message), " "), summaryValueLength)
+	if message == "" {
+		return f
+	}
+	return append(f, summaryFact{factUserSaid, message})
+}
+
+// fit drops the oldest lines of what the user said until the digest is
+// within budget tokens.
+func (f summaryFacts) fit(budget int) summaryFacts {
+	for estimateTokens(f.String()) > budget {
+		i := slices.IndexFunc(f, func(fact summaryFact) bool { return fact.key == factUserSaid })
+		if i < 0 {
+			break
+		}
+		f = append(f[:i:i], f[i+1:]...)
+	}
+	return f
+}
+
+// add records what the user said in a message, and the flights and
+// accommodation a message offered or booked.
+func (f summaryFacts) add(msg models.ChatHistory) summaryFacts {
+	// Structured answers are recorded by what they booked or changed.
+	if msg.UserOrAgent == "user" && msg.ContentType == models.ContentText {
+		f = f.note(msg.Message)
+	}
+
+	var plans []models.TripPlans
+	var flight models.Flights
+	if json.Unmarshal([]byte(msg.FlightObject), &plans) == nil && len(plans) > 0 {
+		offers := make([]string, 0, len(plans))
+		for _, plan := range plans {
+			offers = append(offers, plan.Mode+" "+describeLeg(plan.SelectedFlight.OutboundFlight))
+		}
+		f = f.set(factFlightsOffered, strings.Join(offers, ", "))
+	} else if json.Unmarshal([]byte(msg.FlightObject), &flight) == nil && flight.FlightNumber != "" {
+		f = f.set(factFlightBooked, fmt.Sprintf("%s %s-%s departing %s", flight.FlightNumber,
+			flight.DepartureAirport, flight.ArrivalAirport, time.Time(flight.DepartureTime).Format(time.RFC3339)))
+	}
+
+	var recommendations []models.AccommodationRecommendation
+	var accommodation models.Accommodations
+	if json.Unmarshal([]byte(msg.AccommodationObject), &recommendations) == nil && len(recommendations) > 0 {
+		offers := make([]string, 0, len(recommendations))
+		for _, rec := range recommendations {
+			if rec.Accommodation != nil {
+				offers = append(offers, rec.Accommodation.Name)
+			} else {
+				offers = append(offers, rec.AccommodationID)
+			}
+		}
+		f = f.set(factAccommodationsOffered, strings.Join(offers, ", "))
+	} else if json.Unmarshal([]byte(msg.AccommodationObject), &accommodation) == nil && accommodation.AccommodationID != "" {
+		f = f.set(factAccommodationBooked, accommodation.Name+" in "+accommodation.City)
+	}
+
+	return f
+}
+
+// addTrip records the trip's stage and the requirements it has captured,
+// dropping any that were cleared since and bookings a change invalidated.
+func (f summaryFacts) addTrip(trip models.Trip) summaryFacts {
+	if trip.Stage != "" {
+		f = f.set(factStage, trip.Stage)
+	}
+	switch trip.Stage {
+	case models.StageCollectingRequirements, models.StageChoosingFlight:
+		f = f.unset(factFlightBooked).unset(factAccommodationBooked)
+	case models.StageChoosingAccommodation:
+		f = f.unset(factAccommodationBooked)
+	}
+
+	v := reflect.ValueOf(trip)
+	for i := 0; i < v.NumField(); i++ {
+		key, ok := requirementKey(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if v.Field(i).IsZero() {
+			f = f.unset(key)
+			continue
+		}
+		value := v.Field(i).Interface()
+		if list, isList := value.(models.StringArray); isList {
+			value = strings.Join(list, ", ")
+		}
+		f = f.set(key, fmt.Sprint(value))
+	}
+	return f
+}
+
+func describeLeg(leg models.Flight) string {
+	if leg.FlightNumber == nil {
+		return "no flight"
+	}
+	description := *leg.FlightNumber
+	if leg.DepartureDate != nil {
+		description += " on " + *leg.DepartureDate
+	}
+	return description
+}
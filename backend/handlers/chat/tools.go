@@ -0,0 +1,254 @@
+package chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/accommodations"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/flights"
+	"github.com/yihao03/Aistronaut/m/v2/lda"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/accommodationsparams"
+	"github.com/yihao03/Aistronaut/m/v2/params/flightsparams"
+)
+
+// Tools offered to the agents when AGENT_TOOLS is set.
+const (
+	ToolSearchFlights        = "search_flights"
+	ToolSearchAccommodations = "search_accommodations"
+	ToolGetAccommodation     = "get_accommodation"
+)
+
+const (
+	// maxToolRounds bounds how many rounds of tool calls an agent makes
+	// before it has to answer.
+	maxToolRounds = 5
+	// maxToolResults caps the records a search returns to the agent.
+	maxToolResults = 20
+)
+
+// toolsEnabled reports whether agents search the catalog themselves through
+// tools instead of being handed every candidate up front. Set AGENT_TOOLS
+// once the deployed functions speak the tool protocol.
+func toolsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("AGENT_TOOLS"))
+	return enabled
+}
+
+var searchFlightsTool = lda.Tool{
+	ToolSpec: lda.ToolSpec{
+		Name:        ToolSearchFlights,
+		Description: "Search scheduled flights, earliest departure first. All arguments are optional.",
+		Parameters: lda.Object(nil, map[string]*lda.Schema{
+			"departure_airport": lda.Of("string").Describe("IATA code of the departure airport, e.g. SIN"),
+			"arrival_airport":   lda.Of("string").Describe("IATA code of the arrival airport, e.g. NRT"),
+			"departure_date":    lda.Of("string").Describe("Exact departure date, YYYY-MM-DD"),
+			"start_date":        lda.Of("string").Describe("Earliest departure, YYYY-MM-DD"),
+			"end_date":          lda.Of("string").Describe("Latest departure, YYYY-MM-DD"),
+			"passengers":        lda.Of("string").Describe("Seats needed, e.g. \"2\""),
+		}),
+	},
+	Run: func(_ context.Context, arguments json.RawMessage) (any, error) {
+		var params flightsparams.SearchParams
+		if err := json.Unmarshal(arguments, &params); err != nil {
+			return nil, err
+		}
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		found, err := flights.FindFlights(params)
+		if err != nil {
+			return nil, err
+		}
+		return searchResult("flights", found), nil
+	},
+}
+
+var searchAccommodationsTool = lda.Tool{
+	ToolSpec: lda.ToolSpec{
+		Name:        ToolSearchAccommodations,
+		Description: "Search accommodations, best rated first. All arguments are optional.",
+		Parameters: lda.Object(nil, map[string]*lda.Schema{
+			"destination": lda.Of("string").Describe("City or country"),
+			"type":        lda.Of("string").Describe("One of hotel, hostel, apartment, resort, villa, guesthouse, bnb"),
+		}),
+	},
+	Run: func(_ context.Context, arguments json.RawMessage) (any, error) {
+		var params accommodationsparams.SearchParams
+		if err := json.Unmarshal(arguments, &params); err != nil {
+			return nil, err
+		}
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		found, err := accommodations.FindAccommodations(params)
+		if err != nil {
+			return nil, err
+		}
+		return searchResult("accommodations", found), nil
+	},
+}
+
+var getAccommodationTool = lda.Tool{
+	ToolSpec: lda.ToolSpec{
+		Name:        ToolGetAccommodation,
+		Description: "Get the full details of one accommodation.",
+		Parameters: lda.Object([]string{"accommodation_id"}, map[string]*lda.Schema{
+			"accommodation_id": lda.Of("string"),
+		}),
+	},
+	Run: func(_ context.Context, arguments json.RawMessage) (any, error) {
+		var args struct {
+			AccommodationID string `json:"accommodation_id"`
+		}
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return nil, err
+		}
+		return accommodations.FindAccommodationByID(args.AccommodationID)
+	},
+}
+
+// searchResult returns at most maxToolResults records under name, with the
+// total count so the agent knows to narrow its search.
+func searchResult[T any](name string, records []T) map[string]any {
+	result := map[string]any{"count": len(records)}
+	if len(records) > maxToolResults {
+		records = records[:maxToolResults]
+		result["truncated"] = true
+	}
+	result[name] = records
+	return result
+}
+
+func flightTools() *lda.Toolbox {
+	return lda.NewToolbox(maxToolRounds, searchFlightsTool)
+}
+
+func accommodationTools() *lda.Toolbox {
+	return lda.NewToolbox(maxToolRounds, searchAccommodationsTool, getAccommodationTool)
+}
+
+// withTools wraps an agent call so that it runs the tool loop.
+func withTools(function string, call agentCall, toolbox *lda.Toolbox) agentCall {
+	return func(ctx context.Context, payload lda.LambdaPayload) (*lda.LambdaResponse, error) {
+		return lda.RunTools(ctx, function, call, payload, toolbox)
+	}
+}
+
+const (
+	// traceValueLength caps each value of a stored request and each stored
+	// tool result. Longer ones keep their start, size and hash.
+	traceValueLength = 1000
+	// traceItemLength keeps a stored trace well under DynamoDB's 400 KB item
+	// limit. Past it, requests are stored with their size only.
+	traceItemLength = 300 * 1024
+)
+
+// tracedCall is an lda.AgentCall as stored, with the size of its whole
+// payload.
+type tracedCall struct {
+	Function string         `json:"function"`
+	Kind     lda.Kind       `json:"kind"`
+	Bytes    int            `json:"bytes"`
+	Payload  map[string]any `json:"payload,omitempty"`
+}
+
+// saveTrace stores the agent requests and tool steps of a turn with the
+// agent message that answered it, with long values clipped so the item stays
+// within DynamoDB's size limit. Turns that called no agent, e.g. ones
+// answered from the flight decision cache, store nothing.
+func saveTrace(trip *models.Trip, chatID string, trace *lda.Trace) error {
+	calls, steps := trace.Calls(), trace.Steps()
+	if len(calls) == 0 && len(steps) == 0 {
+		return nil
+	}
+
+	traced := make([]tracedCall, 0, len(calls))
+	for _, call := range calls {
+		payload, err := json.Marshal(call.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal trace: %v", err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return fmt.Errorf("failed to marshal trace: %v", err)
+		}
+		for name, value := range fields {
+			fields[name] = clipValue(value)
+		}
+		traced = append(traced, tracedCall{Function: call.Function, Kind: call.Kind, Bytes: len(payload), Payload: fields})
+	}
+	for i := range steps {
+		if len(steps[i].Result) > traceValueLength {
+			result, err := json.Marshal(clip(string(steps[i].Result)))
+			if err != nil {
+				return fmt.Errorf("failed to marshal trace: %v", err)
+			}
+			steps[i].Result = result
+		}
+	}
+
+	callsJSON, err := json.Marshal(traced)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %v", err)
+	}
+	stepsJSON, err := json.Marshal(steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trace: %v", err)
+	}
+	if len(callsJSON)+len(stepsJSON) > traceItemLength {
+		for i := range traced {
+			traced[i].Payload = nil
+		}
+		if callsJSON, err = json.Marshal(traced); err != nil {
+			return fmt.Errorf("failed to marshal trace: %v", err)
+		}
+	}
+
+	record := models.AgentTrace{
+		TripID: trip.TripID,
+		ChatID: chatID,
+		UserID: trip.UserID,
+		Calls:  string(callsJSON),
+		Steps:  string(stepsJSON),
+	}
+	if err := db.GetDB().Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to create trace: %v", err)
+	}
+
+	log.Printf("Stored %d agent calls and %d tool steps for chat %s", len(calls), len(steps), chatID)
+	return nil
+}
+
+// clipValue clips a value of a request as stored: strings, and other values
+// whose JSON is too long, which are then stored as clipped JSON text.
+func clipValue(value any) any {
+	if text, ok := value.(string); ok {
+		return clip(text)
+	}
+	data, err := json.Marshal(value)
+	if err != nil || len(data) <= traceValueLength {
+		return value
+	}
+	return clip(string(data))
+}
+
+// clip keeps the start of text longer than traceValueLength, noting its size
+// and hash so the full value can still be told apart from others.
+func clip(text string) string {
+	if len(text) <= traceValueLength {
+		return text
+	}
+	hash := sha256.Sum256([]byte(text))
+	// Cutting at a byte count may split a character, which is dropped.
+	return fmt.Sprintf("%s… [%d bytes, sha256 %s]",
+		strings.ToValidUTF8(text[:traceValueLength], ""), len(text), hex.EncodeToString(hash[:8]))
+}
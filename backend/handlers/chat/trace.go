@@ -0,0 +1,45 @@
+package chat
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/view/chatview"
+)
+
+// TraceHandler returns the requests sent to the agents and the tool calls
+// they made while writing an agent message. Messages written without the
+// agents have an empty trace.
+func TraceHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	db := db.GetDB()
+	chatID := c.Param("chat_id")
+
+	var msg models.ChatHistory
+	if err := db.Find(&msg, "chat_history_id = ? AND chat_id = ?", trip.TripID, chatID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_message") + ": " + err.Error()})
+		return
+	}
+	if msg.ChatID == "" {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.message_not_found")})
+		return
+	}
+
+	var trace models.AgentTrace
+	if err := db.Find(&trace, "trip_id = ? AND chat_id = ?", trip.TripID, chatID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_trace") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, chatview.NewTraceResponse(trip.TripID, chatID, trace))
+}
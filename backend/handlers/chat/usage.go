@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/view/usageview"
+)
+
+// UsageHandler totals what the agent calls of a conversation cost, in all
+// and per function.
+func UsageHandler(c *gin.Context) {
+	userID, ok := authorizedUserID(c)
+	if !ok {
+		return
+	}
+
+	trip, ok := findOwnedTrip(c, c.Param("conversation_id"), userID)
+	if !ok {
+		return
+	}
+
+	var records []models.AgentUsage
+	if err := db.GetDB().Find(&records, "trip_id = ?", trip.TripID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_usage") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, usageview.NewConversationUsageResponse(trip.TripID, records))
+}
@@ -1,21 +1,37 @@
 package chat
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 )
 
+// systemTripFields are managed by the backend and never taken from the agent.
+var systemTripFields = map[string]bool{
+	"TripID":       true,
+	"UserID":       true,
+	"Stage":        true,
+	"ActiveLeafID": true,
+
+	"CreatedAt":          true,
+	"LastActivityAt":     true,
+	"LastMessagePreview": true,
+}
+
 func CheckDetailsComplete(trip *models.Trip) bool {
 	v := reflect.ValueOf(trip).Elem() // Use .Elem() to dereference the pointer
 	done := true
 	for i := 0; i < v.NumField(); i++ {
-		fieldName := v.Type().Field(i).Name
-		if fieldName == "TripID" || fieldName == "UserID" || fieldName == "ChildrenCount" || fieldName == "InfantsCount" {
+		field := v.Type().Field(i)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if systemTripFields[field.Name] || models.OptionalTripFields[jsonName] {
 			continue
 		}
 		if v.Field(i).IsZero() {
@@ -29,20 +45,136 @@ func CheckDetailsComplete(trip *models.Trip) bool {
 func HasFlightDetails(trip *models.Trip) bool {
 	db := db.GetDB()
 
-	booking := models.FlightBookings{}
-	if err := db.Find(&booking, "trip_id = ?", trip.TripID).Error; err != nil {
+	var bookings []models.FlightBookings
+	if err := db.Find(&bookings, "trip_id = ?", trip.TripID).Error; err != nil {
 		return false
 	}
 
-	return booking.BookingID != ""
+	for _, booking := range bookings {
+		if !booking.Invalidated {
+			return true
+		}
+	}
+	return false
+}
+
+func HasAccommodationDetails(trip *models.Trip) bool {
+	db := db.GetDB()
+
+	var bookings []models.AccommodationBookings
+	if err := db.Find(&bookings, "trip_id = ?", trip.TripID).Error; err != nil {
+		return false
+	}
+
+	for _, booking := range bookings {
+		if !booking.Invalidated {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveStage returns the trip's stage. Trips created before stages were
+// stored have theirs inferred once from the trip and its bookings and saved.
+func resolveStage(trip *models.Trip) (string, error) {
+	if trip.Stage != "" {
+		return trip.Stage, nil
+	}
+
+	stage := models.StageCollectingRequirements
+	switch {
+	case HasAccommodationDetails(trip):
+		stage = models.StageReviewing
+	case HasFlightDetails(trip):
+		stage = models.StageChoosingAccommodation
+	case CheckDetailsComplete(trip):
+		stage = models.StageChoosingFlight
+	}
+
+	db := db.GetDB()
+	if err := db.Model(trip).Update("stage", stage).Error; err != nil {
+		return "", fmt.Errorf("failed to save stage: %v", err)
+	}
+	trip.Stage = stage
+
+	return stage, nil
+}
+
+// setStage validates and persists a stage transition.
+func setStage(trip *models.Trip, stage string) error {
+	if err := models.ValidateStageTransition(trip.Stage, stage); err != nil {
+		return err
+	}
+
+	db := db.GetDB()
+	if err := db.Model(trip).Update("stage", stage).Error; err != nil {
+		return fmt.Errorf("failed to save stage: %v", err)
+	}
+	trip.Stage = stage
+
+	return nil
 }
 
-func parseResponse(response string) (*FinalResponse, error) {
-	var finalResp FinalResponse
-	responseStr := strings.TrimPrefix(response, "```json\n")
-	responseStr = strings.TrimSuffix(responseStr, "\n```")
-	if err := json.Unmarshal([]byte(responseStr), &finalResp); err != nil {
-		return nil, fmt.Errorf("failed to parse inner response: %v", err)
+// rewindStage moves a trip back to an earlier stage, skipping stages if needed.
+func rewindStage(trip *models.Trip, stage string) error {
+	if err := models.ValidateStageRewind(trip.Stage, stage); err != nil {
+		return err
 	}
-	return &finalResp, nil
+
+	db := db.GetDB()
+	if err := db.Model(trip).Update("stage", stage).Error; err != nil {
+		return fmt.Errorf("failed to save stage: %v", err)
+	}
+	trip.Stage = stage
+
+	return nil
+}
+
+// tripDate normalises a trip date, stored either as RFC3339 or as a plain
+// date, to YYYY-MM-DD.
+func tripDate(date string) string {
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return date
+}
+
+// authorizedUserID returns the user ID from the request's token, writing the
+// error response itself when there is none.
+func authorizedUserID(c *gin.Context) (string, bool) {
+	claims, err := myjwt.ParseJWTFromContext(c)
+	if err != nil {
+		c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+		return "", false
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+		return "", false
+	}
+	return userID, true
+}
+
+// findOwnedTrip loads the trip behind a conversation and resolves its stage,
+// writing the error response itself when the trip is missing or belongs to
+// another user.
+func findOwnedTrip(c *gin.Context, conversationID string, userID string) (*models.Trip, bool) {
+	db := db.GetDB()
+
+	var trip models.Trip
+	if err := db.Find(&trip, "trip_id = ?", conversationID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_trip") + ": " + err.Error()})
+		return nil, false
+	}
+	if trip.TripID == "" || trip.UserID != userID {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.conversation_not_found")})
+		return nil, false
+	}
+
+	if _, err := resolveStage(&trip); err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_resolve_stage") + ": " + err.Error()})
+		return nil, false
+	}
+
+	return &trip, true
 }
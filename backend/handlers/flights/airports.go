@@ -0,0 +1,121 @@
+package flights
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// loadAirports reads the directory of airports the catalog serves.
+func loadAirports() ([]models.Airports, error) {
+	db := db.GetDB()
+
+	var airports []models.Airports
+	if err := db.Find(&airports).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve airports: %v", err)
+	}
+	return airports, nil
+}
+
+// countryAliases maps the other ways users name a country, including the
+// nationalities stored on their profile, to the directory's name.
+var countryAliases = map[string]string{
+	"us":                       "United States",
+	"usa":                      "United States",
+	"america":                  "United States",
+	"american":                 "United States",
+	"united states of america": "United States",
+	"canadian":                 "Canada",
+	"mexican":                  "Mexico",
+	"brazilian":                "Brazil",
+	"uk":                       "United Kingdom",
+	"britain":                  "United Kingdom",
+	"great britain":            "United Kingdom",
+	"england":                  "United Kingdom",
+	"scotland":                 "United Kingdom",
+	"british":                  "United Kingdom",
+	"english":                  "United Kingdom",
+	"scottish":                 "United Kingdom",
+	"irish":                    "Ireland",
+	"french":                   "France",
+	"dutch":                    "Netherlands",
+	"holland":                  "Netherlands",
+	"the netherlands":          "Netherlands",
+	"german":                   "Germany",
+	"swiss":                    "Switzerland",
+	"austrian":                 "Austria",
+	"italian":                  "Italy",
+	"spanish":                  "Spain",
+	"portuguese":               "Portugal",
+	"greek":                    "Greece",
+	"turkish":                  "Turkey",
+	"turkiye":                  "Turkey",
+	"uae":                      "United Arab Emirates",
+	"emirati":                  "United Arab Emirates",
+	"qatari":                   "Qatar",
+	"egyptian":                 "Egypt",
+	"south african":            "South Africa",
+	"indian":                   "India",
+	"singaporean":              "Singapore",
+	"malaysian":                "Malaysia",
+	"thai":                     "Thailand",
+	"indonesian":               "Indonesia",
+	"filipino":                 "Philippines",
+	"vietnamese":               "Vietnam",
+	"viet nam":                 "Vietnam",
+	"hongkonger":               "Hong Kong",
+	"taiwanese":                "Taiwan",
+	"chinese":                  "China",
+	"prc":                      "China",
+	"korea":                    "South Korea",
+	"korean":                   "South Korea",
+	"south korean":             "South Korea",
+	"japanese":                 "Japan",
+	"australian":               "Australia",
+	"new zealander":            "New Zealand",
+	"kiwi":                     "New Zealand",
+}
+
+// normalizeCountry returns the directory's name for a country or
+// nationality, or the trimmed input if it is not known.
+func normalizeCountry(airports []models.Airports, name string) string {
+	name = strings.TrimSpace(name)
+	if country, ok := countryAliases[strings.ToLower(name)]; ok {
+		return country
+	}
+	for _, airport := range airports {
+		if strings.EqualFold(airport.Country, name) {
+			return airport.Country
+		}
+	}
+	return name
+}
+
+// airportsIn returns the codes of the airports in any of the given cities,
+// falling back to the airports in any of the given countries when none of
+// the cities has a known airport.
+func airportsIn(airports []models.Airports, cities []string, countries []string) map[string]bool {
+	codes := map[string]bool{}
+	for _, airport := range airports {
+		for _, city := range cities {
+			if strings.EqualFold(airport.City, strings.TrimSpace(city)) {
+				codes[airport.AirportCode] = true
+			}
+		}
+	}
+	if len(codes) > 0 {
+		return codes
+	}
+
+	for _, country := range countries {
+		country = normalizeCountry(airports, country)
+		for _, airport := range airports {
+			if airport.Country == country {
+				codes[airport.AirportCode] = true
+			}
+		}
+	}
+	return codes
+}
@@ -0,0 +1,165 @@
+package flights
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// Candidates are the flights worth offering for a trip: outbound flights
+// from the trip's origin to the destination and return flights back, each
+// best first.
+type Candidates struct {
+	Outbound []models.Flights `json:"outbound"`
+	Return   []models.Flights `json:"return"`
+}
+
+// Weights of a flight's price, duration and stops in its score. Each is
+// normalised over the flights being ranked, so a score of 0 is the
+// cheapest, shortest, direct flight.
+const (
+	priceWeight    = 0.5
+	durationWeight = 0.3
+	stopsWeight    = 0.2
+)
+
+// FindCandidates builds the candidate flights for a trip. Both sets only hold
+// scheduled flights departing during the trip with a seat for every traveller
+// and an economy fare for all of them within the budget, and are capped at
+// limit flights. The trip starts from its origin city, or from anywhere in the
+// traveller's home country when it has none. A side whose airports are not
+// known, such as a city missing from the directory, is not filtered on.
+func FindCandidates(trip *models.Trip, homeCountry string, limit int) (*Candidates, error) {
+	start, err := time.Parse(time.RFC3339, trip.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %v", err)
+	}
+	end, err := time.Parse("2006-01-02", trip.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %v", err)
+	}
+
+	db := db.GetDB()
+	var flights []models.Flights
+
+	// The end date is a whole day, so return flights leaving on it count.
+	query := db.Where("departure_time >= ? AND departure_time < ?", start, end.AddDate(0, 0, 1))
+	query = query.Where("status = ?", "Scheduled")
+	if trip.NumberOfTravelers > 0 {
+		query = query.Where("available_seats >= ?", trip.NumberOfTravelers)
+	}
+	if err := query.Find(&flights).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve flights: %v", err)
+	}
+
+	airports, err := loadAirports()
+	if err != nil {
+		return nil, err
+	}
+	home := airportsIn(airports, nil, []string{homeCountry})
+	if trip.OriginCity != "" {
+		home = airportsIn(airports, []string{trip.OriginCity}, nil)
+	}
+	destination := airportsIn(airports, trip.DestinationCities, trip.DestinationCountry)
+
+	travelers := max(trip.NumberOfTravelers, 1)
+	var outbound, inbound []models.Flights
+	for _, flight := range flights {
+		if trip.TotalBudget > 0 && flight.PriceEconomy*float64(travelers) > float64(trip.TotalBudget) {
+			continue
+		}
+		if connects(home, destination, flight) {
+			outbound = append(outbound, flight)
+		} else if connects(destination, home, flight) {
+			inbound = append(inbound, flight)
+		}
+	}
+
+	return &Candidates{
+		Outbound: rank(outbound, limit),
+		Return:   rank(inbound, limit),
+	}, nil
+}
+
+// connects reports whether a flight leaves from one of the from airports
+// and lands at one of the to airports. An empty set matches any airport,
+// but a flight never connects a set to itself.
+func connects(from, to map[string]bool, flight models.Flights) bool {
+	if len(from) == 0 && len(to) == 0 {
+		return false
+	}
+	if len(from) > 0 && !from[flight.DepartureAirport] {
+		return false
+	}
+	if len(to) > 0 && !to[flight.ArrivalAirport] {
+		return false
+	}
+	return !(from[flight.ArrivalAirport] || to[flight.DepartureAirport])
+}
+
+// rank orders flights by score, breaking ties by flight ID and departure so
+// the same flights are always offered in the same order, and keeps the
+// first limit of them. A limit of 0 or less keeps them all.
+func rank(flights []models.Flights, limit int) []models.Flights {
+	var maxPrice, maxDuration, maxStops float64
+	for _, flight := range flights {
+		maxPrice = math.Max(maxPrice, flight.PriceEconomy)
+		maxDuration = math.Max(maxDuration, float64(flight.DurationMinutes))
+		maxStops = math.Max(maxStops, float64(stops(flight)))
+	}
+
+	scores := make(map[string]float64, len(flights))
+	for _, flight := range flights {
+		scores[flightKey(flight)] = priceWeight*ratio(flight.PriceEconomy, maxPrice) +
+			durationWeight*ratio(float64(flight.DurationMinutes), maxDuration) +
+			stopsWeight*ratio(float64(stops(flight)), maxStops)
+	}
+
+	sort.SliceStable(flights, func(i, j int) bool {
+		a, b := flights[i], flights[j]
+		if scoreA, scoreB := scores[flightKey(a)], scores[flightKey(b)]; scoreA != scoreB {
+			return scoreA < scoreB
+		}
+		if a.FlightID != b.FlightID {
+			return a.FlightID < b.FlightID
+		}
+		return time.Time(a.DepartureTime).Before(time.Time(b.DepartureTime))
+	})
+
+	if limit > 0 && len(flights) > limit {
+		flights = flights[:limit]
+	}
+	return flights
+}
+
+func flightKey(flight models.Flights) string {
+	return flight.FlightID + "|" + time.Time(flight.DepartureTime).Format(time.RFC3339)
+}
+
+func ratio(value, max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return value / max
+}
+
+// stops reads the number of stops from a flight's layovers, which hold
+// "Direct", a count such as "1 stop" or a list of airports such as
+// "DXB, IST".
+func stops(flight models.Flights) int {
+	layovers := strings.TrimSpace(flight.Layovers)
+	switch strings.ToLower(layovers) {
+	case "", "direct", "none", "non-stop", "nonstop":
+		return 0
+	}
+	if count, err := strconv.Atoi(strings.Fields(layovers)[0]); err == nil {
+		return count
+	}
+	return len(strings.Split(layovers, ","))
+}
@@ -2,31 +2,57 @@ package flights
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/yihao03/Aistronaut/m/v2/db"
 	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/params/flightsparams"
 )
 
-// GetFlightsByDateRange retrieves flights within a specified date range
-func GetFlightsByDateRange(startDate, endDate string) ([]models.Flights, error) {
-	st, err := time.Parse(time.RFC3339, startDate)
-	if err != nil {
-		return nil, fmt.Errorf(time.RFC3339, err)
-	}
-	et, err := time.Parse("2006-01-02", endDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %v", err)
-	}
+// FindFlights runs a flight search: scheduled flights matching the given
+// airports, departure date or departure window and seat count, earliest
+// departure first.
+func FindFlights(params flightsparams.SearchParams) ([]models.Flights, error) {
 	db := db.GetDB()
 	var flights []models.Flights
 
-	// Build query for flights within the date range
-	query := db.Where("departure_time >= ? AND departure_time <= ?", st, et)
+	// Build search query
+	query := db
+
+	if params.DepartureAirport != nil {
+		query = query.Where("departure_airport = ?", *params.DepartureAirport)
+	}
+
+	if params.ArrivalAirport != nil {
+		query = query.Where("arrival_airport = ?", *params.ArrivalAirport)
+	}
+
+	if params.DepartureDate != nil {
+		query = query.Where("DATE(departure_time) = ?", *params.DepartureDate)
+	}
+
+	if params.StartDate != nil {
+		query = query.Where("departure_time >= ?", *params.StartDate)
+	}
+
+	if params.EndDate != nil {
+		query = query.Where("departure_time <= ?", *params.EndDate)
+	}
+
+	// Filter by available seats
+	if params.Passengers != nil {
+		if passengerCount, err := params.GetPassengersInt(); err == nil && passengerCount > 0 {
+			query = query.Where("available_seats >= ?", passengerCount)
+		}
+	}
+
+	// Only show scheduled flights
+	query = query.Where("status = ?", "Scheduled")
+
+	// Order by departure time
+	query = query.Order("departure_time ASC")
 
-	// Execute query
 	if err := query.Find(&flights).Error; err != nil {
-		return nil, fmt.Errorf("failed to retrieve flights: %v", err)
+		return nil, fmt.Errorf("failed to search flights: %v", err)
 	}
 
 	return flights, nil
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/flightsparams"
 )
@@ -18,7 +19,7 @@ func GetAllFlights(c *gin.Context) {
 	var params flightsparams.SearchParams
 	if err := c.ShouldBindQuery(&params); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid query parameters",
+			"error":   i18n.T(c, "error.invalid_query_parameters"),
 			"details": err.Error(),
 		})
 		return
@@ -27,7 +28,7 @@ func GetAllFlights(c *gin.Context) {
 	// Validate parameters
 	if err := params.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Validation failed",
+			"error":   i18n.T(c, "error.validation_failed"),
 			"details": err.Error(),
 		})
 		return
@@ -75,7 +76,7 @@ func GetAllFlights(c *gin.Context) {
 	// Execute query
 	if err := query.Find(&flights).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to retrieve flights",
+			"error":   i18n.T(c, "error.failed_to_retrieve_flights"),
 			"details": err.Error(),
 		})
 		return
@@ -85,7 +86,7 @@ func GetAllFlights(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"flights": flights,
 		"count":   len(flights),
-		"message": "Flights retrieved successfully",
+		"message": i18n.T(c, "message.flights_retrieved"),
 	})
 }
 
@@ -99,7 +100,7 @@ func GetFlightByID(c *gin.Context) {
 	// Find flight by ID
 	if err := db.Where("flight_id = ?", flightID).First(&flight).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
-			"error":     "Flight not found",
+			"error":     i18n.T(c, "error.flight_not_found"),
 			"flight_id": flightID,
 		})
 		return
@@ -107,20 +108,17 @@ func GetFlightByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"flight":  flight,
-		"message": "Flight retrieved successfully",
+		"message": i18n.T(c, "message.flight_retrieved"),
 	})
 }
 
 // SearchFlights provides advanced search functionality
 func SearchFlights(c *gin.Context) {
-	db := db.GetDB()
-	var flights []models.Flights
-
 	// Bind search parameters to struct
 	var params flightsparams.SearchParams
 	if err := c.ShouldBindQuery(&params); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid search parameters",
+			"error":   i18n.T(c, "error.invalid_search_parameters"),
 			"details": err.Error(),
 		})
 		return
@@ -129,43 +127,16 @@ func SearchFlights(c *gin.Context) {
 	// Validate parameters
 	if err := params.Validate(); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Validation failed",
+			"error":   i18n.T(c, "error.validation_failed"),
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Build search query
-	query := db
-
-	if params.DepartureAirport != nil {
-		query = query.Where("departure_airport = ?", *params.DepartureAirport)
-	}
-
-	if params.ArrivalAirport != nil {
-		query = query.Where("arrival_airport = ?", *params.ArrivalAirport)
-	}
-
-	if params.DepartureDate != nil {
-		query = query.Where("DATE(departure_time) = ?", *params.DepartureDate)
-	}
-
-	// Filter by available seats
-	if params.Passengers != nil {
-		if passengerCount, err := params.GetPassengersInt(); err == nil && passengerCount > 0 {
-			query = query.Where("available_seats >= ?", passengerCount)
-		}
-	}
-
-	// Only show scheduled flights
-	query = query.Where("status = ?", "Scheduled")
-
-	// Order by departure time
-	query = query.Order("departure_time ASC")
-
-	if err := query.Find(&flights).Error; err != nil {
+	flights, err := FindFlights(params)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to search flights",
+			"error":   i18n.T(c, "error.failed_to_search_flights"),
 			"details": err.Error(),
 		})
 		return
@@ -175,6 +146,6 @@ func SearchFlights(c *gin.Context) {
 		"flights":       flights,
 		"count":         len(flights),
 		"search_params": params,
-		"message":       "Flight search completed successfully",
+		"message":       i18n.T(c, "message.flight_search_completed"),
 	})
 }
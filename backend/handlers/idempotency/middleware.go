@@ -0,0 +1,199 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+)
+
+const (
+	// HeaderKey is the request header carrying the client's idempotency key.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on responses replayed from an earlier request.
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// A request still in progress after lockTimeout is assumed to have died
+	// and no longer blocks its key.
+	lockTimeout = 5 * time.Minute
+	// Responses are replayed for retention after the first request.
+	retention = 24 * time.Hour
+
+	// discardKey marks a request whose response must not be stored.
+	discardKey = "idempotency.discard"
+)
+
+// Middleware makes a POST endpoint safe to retry. The first request a user
+// sends with a given Idempotency-Key runs normally and its response is
+// stored; retries with the same key get that response back without running
+// the handler again. A retry that arrives while the first request is still
+// running is rejected with 409. Server errors and responses the handler
+// discards are not stored, so the request can be retried. Requests without
+// the header are not affected.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.idempotency_key_too_long", HeaderKey, maxKeyLength)})
+			c.Abort()
+			return
+		}
+
+		claims, err := myjwt.ParseJWTFromContext(c)
+		if err != nil {
+			c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+			c.Abort()
+			return
+		}
+		userID, ok := claims["user_id"].(string)
+		if !ok {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.failed_to_read_request_body") + ": " + err.Error()})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := models.IdempotencyRecord{
+			UserID:         userID,
+			IdempotencyKey: key,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			RequestHash:    requestHash(c.Request.Method, c.Request.URL.Path, body),
+			Status:         models.IdempotencyInProgress,
+		}
+		earlier, err := reserve(&record)
+		if err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_reserve_idempotency_key") + ": " + err.Error()})
+			c.Abort()
+			return
+		}
+
+		if earlier != nil {
+			switch {
+			case earlier.RequestHash != record.RequestHash:
+				c.JSON(422, gin.H{"error": i18n.T(c, "error.idempotency_key_reused", HeaderKey)})
+			case earlier.Status == models.IdempotencyInProgress:
+				c.JSON(409, gin.H{"error": i18n.T(c, "error.idempotency_key_in_progress", HeaderKey)})
+			default:
+				c.Header(HeaderReplayed, "true")
+				c.Data(earlier.ResponseStatus, earlier.ContentType, []byte(earlier.ResponseBody))
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if err := finish(&record, writer, c.GetBool(discardKey)); err != nil {
+			log.Printf("Failed to store response for %s %q: %v", HeaderKey, key, err)
+		}
+	}
+}
+
+// reserve claims the record's key for a new request. It returns nil once
+// the claim succeeded, or the record of the earlier request holding the key.
+func reserve(record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	db := db.GetDB()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var existing models.IdempotencyRecord
+		if err := db.Find(&existing, "user_id = ? AND idempotency_key = ?", record.UserID, record.IdempotencyKey).Error; err != nil {
+			return nil, fmt.Errorf("failed to find idempotency record: %v", err)
+		}
+		if existing.UserID != "" {
+			if !expired(&existing) {
+				return &existing, nil
+			}
+			if err := db.Delete(&existing).Error; err != nil {
+				return nil, fmt.Errorf("failed to delete expired idempotency record: %v", err)
+			}
+		}
+
+		// Creating fails if a concurrent request claimed the key first, in
+		// which case the next attempt finds its record.
+		if err = db.Create(record).Error; err == nil {
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to create idempotency record: %v", err)
+}
+
+func expired(record *models.IdempotencyRecord) bool {
+	if record.Status == models.IdempotencyInProgress {
+		return time.Since(time.Time(record.UpdatedAt)) > lockTimeout
+	}
+	return time.Since(time.Time(record.CreatedAt)) > retention
+}
+
+// Discard keeps the response to the current request from being stored, so a
+// retry with the same key runs again. Handlers that report a failure in a
+// successful response, such as a stream ending in an error event, call it.
+func Discard(c *gin.Context) {
+	c.Set(discardKey, true)
+}
+
+// finish stores the response of a request that holds its key, or releases
+// the key if the request failed with a server error or was discarded.
+func finish(record *models.IdempotencyRecord, writer *recordingWriter, discard bool) error {
+	db := db.GetDB()
+
+	if discard || writer.Status() >= 500 {
+		return db.Delete(record).Error
+	}
+
+	return db.Model(record).Updates(map[string]any{
+		"status":          models.IdempotencyCompleted,
+		"response_status": writer.Status(),
+		"content_type":    writer.Header().Get("Content-Type"),
+		"response_body":   writer.body.String(),
+		"updated_at":      models.Now(),
+	}).Error
+}
+
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", method, path)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter keeps a copy of everything written to the response.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,64 @@
+package trip
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+	"github.com/yihao03/Aistronaut/m/v2/params/tripparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/tripview"
+)
+
+// HandleItinerary returns the itineraries planned for a trip, one per mode,
+// or only the one for the mode given in the query.
+func HandleItinerary(c *gin.Context) {
+	db := db.GetDB()
+	var params tripparams.ItineraryParams
+
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_query_parameters") + ": " + err.Error()})
+		return
+	}
+
+	claims, err := myjwt.ParseJWTFromContext(c)
+	if err != nil {
+		c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+		return
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+		return
+	}
+
+	var trip models.Trip
+	if err := db.Find(&trip, "trip_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_trip") + ": " + err.Error()})
+		return
+	}
+	if trip.TripID == "" || trip.UserID != userID {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.trip_not_found")})
+		return
+	}
+
+	query := db.Where("trip_id = ?", trip.TripID)
+	if params.Mode != nil && *params.Mode != "" {
+		query = query.Where("mode = ?", *params.Mode)
+	}
+
+	var itineraries []models.Itinerary
+	if err := query.Find(&itineraries).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_itineraries") + ": " + err.Error()})
+		return
+	}
+	if len(itineraries) == 0 {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.no_itinerary")})
+		return
+	}
+
+	c.JSON(200, tripview.ItineraryResponse{
+		TripID:      trip.TripID,
+		Itineraries: itineraries,
+	})
+}
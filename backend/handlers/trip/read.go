@@ -3,6 +3,7 @@ package trip
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/params/tripparams"
 	"github.com/yihao03/Aistronaut/m/v2/view/tripview"
@@ -19,13 +20,13 @@ func HandleRead(c *gin.Context) {
 
 	var flightBooking []models.FlightBookings
 	if err := db.Find(&flightBooking, "trip_id = ?", body.TripID).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find flight booking: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_flight_booking") + ": " + err.Error()})
 		return
 	}
 
 	var accommodationBooking []models.AccommodationBookings
 	if err := db.Find(&accommodationBooking, "trip_id = ?", body.TripID).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to find accommodation booking: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_accommodation_booking") + ": " + err.Error()})
 		return
 	}
 
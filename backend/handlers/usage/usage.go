@@ -0,0 +1,198 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/idempotency"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	"github.com/yihao03/Aistronaut/m/v2/lda"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+	"github.com/yihao03/Aistronaut/m/v2/params/userparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/usageview"
+	"gorm.io/gorm"
+)
+
+// DailyQuota returns how many agent calls a user may make per UTC day, set
+// with AGENT_DAILY_QUOTA. Zero means there is no quota.
+func DailyQuota() int {
+	quota, err := strconv.Atoi(os.Getenv("AGENT_DAILY_QUOTA"))
+	if err != nil || quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// Record stores the usage of every agent call collected in trace, made by a
+// user in a conversation.
+func Record(userID, tripID string, trace *lda.Trace) error {
+	db := db.GetDB()
+	day := today()
+
+	for _, usage := range trace.Usage() {
+		record := models.AgentUsage{
+			UserID:           userID,
+			CallID:           uuid.New().String(),
+			TripID:           tripID,
+			Day:              day,
+			Function:         usage.Function,
+			Kind:             string(usage.Kind),
+			Attempts:         usage.Attempts,
+			DurationMs:       usage.Duration.Milliseconds(),
+			BilledDurationMs: usage.BilledDuration.Milliseconds(),
+			RequestBytes:     usage.RequestBytes,
+			ResponseBytes:    usage.ResponseBytes,
+			InputTokens:      usage.InputTokens,
+			OutputTokens:     usage.OutputTokens,
+			Failed:           usage.Failed,
+		}
+		if err := db.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to create usage: %v", err)
+		}
+	}
+
+	if calls := len(trace.Usage()); calls > 0 {
+		return countCalls(userID, day, calls)
+	}
+	return nil
+}
+
+// countCalls adds to the number of agent calls a user made on a day.
+func countCalls(userID, day string, calls int) error {
+	db := db.GetDB()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var counter models.DailyUsage
+		if err := db.Find(&counter, "user_id = ? AND day = ?", userID, day).Error; err != nil {
+			return fmt.Errorf("failed to find daily usage: %v", err)
+		}
+		if counter.UserID != "" {
+			if err := db.Model(&counter).Updates(map[string]any{
+				"calls":      gorm.Expr("calls + ?", calls),
+				"updated_at": models.Now(),
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update daily usage: %v", err)
+			}
+			return nil
+		}
+
+		// Creating fails if a concurrent call counted first, in which case
+		// the next attempt adds to its counter.
+		counter = models.DailyUsage{UserID: userID, Day: day, Calls: calls}
+		if err = db.Create(&counter).Error; err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to create daily usage: %v", err)
+}
+
+// callsOn returns the number of agent calls a user made on a day.
+func callsOn(userID, day string) (int, error) {
+	var counter models.DailyUsage
+	if err := db.GetDB().Find(&counter, "user_id = ? AND day = ?", userID, day).Error; err != nil {
+		return 0, fmt.Errorf("failed to find daily usage: %v", err)
+	}
+	return counter.Calls, nil
+}
+
+// Quota rejects requests with 429 once the signed-in user has used up the
+// daily quota of agent calls, until the next UTC day. It goes after
+// idempotency.Middleware, so a retry of a request that already ran is
+// replayed rather than rejected, and discards its rejections so they are not
+// replayed after the quota resets. Without a quota it does nothing.
+func Quota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := DailyQuota()
+		if limit == 0 {
+			c.Next()
+			return
+		}
+
+		claims, err := myjwt.ParseJWTFromContext(c)
+		if err != nil {
+			c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+			c.Abort()
+			return
+		}
+		userID, ok := claims["user_id"].(string)
+		if !ok {
+			c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+			c.Abort()
+			return
+		}
+
+		used, err := callsOn(userID, today())
+		if err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_usage") + ": " + err.Error()})
+			c.Abort()
+			return
+		}
+		if used >= limit {
+			now := time.Now().UTC()
+			tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			c.Header("Retry-After", strconv.Itoa(int(tomorrow.Sub(now).Seconds())+1))
+			idempotency.Discard(c)
+			c.JSON(429, gin.H{"error": i18n.T(c, "error.daily_quota_exceeded", limit)})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// UserHandler totals the signed-in user's agent calls over a range of days,
+// in all and per day, with what is left of today's quota.
+func UserHandler(c *gin.Context) {
+	var params userparams.UsageParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_query_parameters") + ": " + err.Error()})
+		return
+	}
+	if err := params.Validate(); err != nil {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.validation_failed") + ": " + err.Error()})
+		return
+	}
+
+	claims, err := myjwt.ParseJWTFromContext(c)
+	if err != nil {
+		c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+		return
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+		return
+	}
+
+	from, to := params.Range()
+	var records []models.AgentUsage
+	if err := db.GetDB().Find(&records, "user_id = ? AND day >= ? AND day <= ?", userID, from, to).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_usage") + ": " + err.Error()})
+		return
+	}
+
+	res := usageview.NewUserUsageResponse(from, to, records)
+	if limit := DailyQuota(); limit > 0 {
+		used, err := callsOn(userID, today())
+		if err != nil {
+			c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_usage") + ": " + err.Error()})
+			return
+		}
+		res.Quota = &usageview.QuotaView{Limit: limit, Used: used, Remaining: max(limit-used, 0)}
+	}
+
+	c.JSON(200, res)
+}
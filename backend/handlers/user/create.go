@@ -3,10 +3,12 @@ package user
 import (
 	"net/http"
 	"net/mail"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	model "github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 	"github.com/yihao03/Aistronaut/m/v2/params/userparams"
@@ -23,20 +25,28 @@ func Create(c *gin.Context) {
 	}
 
 	if _, err := mail.ParseAddress(body.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.T(c, "error.invalid_email_format")})
 		return
 	}
 
+	locale := i18n.Locale(c)
+	if body.Locale != "" {
+		if locale = i18n.Match(body.Locale); locale == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.T(c, "error.unsupported_locale", body.Locale, strings.Join(i18n.Locales(), ", "))})
+			return
+		}
+	}
+
 	db := db.GetDB()
 
 	if len(body.Password) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.T(c, "error.password_too_short")})
 		return
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(body.Password), 10)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to hash password"})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_hash_password")})
 		return
 	}
 
@@ -48,17 +58,18 @@ func Create(c *gin.Context) {
 		Email:       body.Email,
 		Password:    string(hashedPassword),
 		Nationality: body.Nationality,
+		Locale:      locale,
 	}
 
 	result := db.Create(&user)
 	if err := result.Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create user: " + err.Error()})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_create_user") + ": " + err.Error()})
 		return
 	}
 
 	jwtToken, err := myjwt.GenerateJWTToken(user)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create token"})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_create_token")})
 		return
 	}
 
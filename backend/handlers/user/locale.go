@@ -0,0 +1,60 @@
+package user
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
+	model "github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+	"github.com/yihao03/Aistronaut/m/v2/params/userparams"
+	"github.com/yihao03/Aistronaut/m/v2/view/userview"
+)
+
+// UpdateLocale stores the locale the signed-in user is answered in. A
+// single request can still ask for another one with ?locale=.
+func UpdateLocale(c *gin.Context) {
+	var body userparams.LocaleParams
+
+	if err := c.Bind(&body); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	locale := i18n.Match(body.Locale)
+	if locale == "" {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.unsupported_locale", body.Locale, strings.Join(i18n.Locales(), ", "))})
+		return
+	}
+
+	claims, err := myjwt.ParseJWTFromContext(c)
+	if err != nil {
+		c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized") + ": " + err.Error()})
+		return
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		c.JSON(400, gin.H{"error": i18n.T(c, "error.invalid_user_id")})
+		return
+	}
+
+	db := db.GetDB()
+
+	var user model.Users
+	if err := db.Find(&user, "user_id = ?", userID).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_find_user") + ": " + err.Error()})
+		return
+	}
+	if user.UserID == "" {
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.user_not_found")})
+		return
+	}
+
+	if err := db.Model(&user).Updates(map[string]any{"locale": locale, "updated_at": model.Now()}).Error; err != nil {
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_update_user") + ": " + err.Error()})
+		return
+	}
+
+	c.JSON(200, userview.LocaleResponse{Locale: locale})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/i18n"
 	model "github.com/yihao03/Aistronaut/m/v2/models"
 	"github.com/yihao03/Aistronaut/m/v2/myjwt"
 	"github.com/yihao03/Aistronaut/m/v2/params/userparams"
@@ -28,19 +29,19 @@ func Login(c *gin.Context) {
 	// find user by username
 	if err := db.Where("email = ?", body.Email).First(&user).Error; err != nil {
 		fmt.Println("user not found")
-		c.JSON(404, gin.H{"error": "User not found"})
+		c.JSON(404, gin.H{"error": i18n.T(c, "error.user_not_found")})
 		return
 	}
 
 	// compare the password from the request body with the hashed password in the database
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
-		c.JSON(401, gin.H{"error": "Invalid password"})
+		c.JSON(401, gin.H{"error": i18n.T(c, "error.invalid_password")})
 		return
 	}
 
 	tokenString, err := myjwt.GenerateJWTToken(user)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create token"})
+		c.JSON(500, gin.H{"error": i18n.T(c, "error.failed_to_create_token")})
 		return
 	}
 
@@ -52,7 +53,7 @@ func Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(403, gin.H{"error": "Unauthorized"})
+			c.JSON(403, gin.H{"error": i18n.T(c, "error.unauthorized")})
 			c.Abort()
 			return
 		}
@@ -63,7 +64,7 @@ func Authenticate() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(401, gin.H{"error": "Invalid token"})
+			c.JSON(401, gin.H{"error": i18n.T(c, "error.invalid_token")})
 			c.Abort()
 			return
 		}
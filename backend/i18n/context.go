@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+	"github.com/yihao03/Aistronaut/m/v2/myjwt"
+)
+
+const (
+	// QueryKey is the query parameter that overrides the locale of a single
+	// request.
+	QueryKey = "locale"
+
+	contextKey = "i18n.locale"
+)
+
+// Locale returns the locale a request is answered in. The first of these
+// that names a supported locale wins: the locale query parameter, the
+// locale stored on the signed-in user, the Accept-Language header, and
+// finally Default. The result is kept on the context, so the user is looked
+// up at most once per request.
+func Locale(c *gin.Context) string {
+	if locale := c.GetString(contextKey); locale != "" {
+		return locale
+	}
+
+	locale := Match(c.Query(QueryKey))
+	if locale == "" {
+		locale = userLocale(c)
+	}
+	if locale == "" {
+		locale = MatchAcceptLanguage(c.GetHeader("Accept-Language"))
+	}
+	if locale == "" {
+		locale = Default
+	}
+
+	c.Set(contextKey, locale)
+	return locale
+}
+
+// T returns the message with the given key in the locale of the request.
+func T(c *gin.Context, key string, args ...any) string {
+	return Translate(Locale(c), key, args...)
+}
+
+func userLocale(c *gin.Context) string {
+	claims, err := myjwt.ParseJWTFromContext(c)
+	if err != nil {
+		return ""
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return ""
+	}
+
+	var user models.Users
+	if err := db.GetDB().Find(&user, "user_id = ?", userID).Error; err != nil {
+		return ""
+	}
+	return Match(user.Locale)
+}
@@ -0,0 +1,136 @@
+package i18n
+
+// en is the English catalog. Every key must be present here, as it is the
+// fallback of the other catalogs.
+var en = map[string]string{
+	"chat.flight_selected":        "Flight %s selected. Let's proceed with accomodations booking",
+	"chat.accommodation_selected": "Accommodation %s selected. Booking confirmed.",
+	"chat.no_accommodation":       "I couldn't find any accommodation in %s. Could you suggest another city?",
+	"chat.reviewing":              "Your flight and accommodation are booked. Review your trip and confirm it when you're ready.",
+	"chat.confirmed":              "Your trip is confirmed. Have a great time!",
+	"chat.change":                 "%s from %s to %s",
+	"chat.pending_changes":        "You'd like to change %s. This will cancel your %s booking. Do you want to go ahead?",
+	"chat.changes_applied":        "Done, I've updated %s. Let's pick up from %s.",
+	"chat.changes_rejected":       "No problem, I've kept your trip as it was.",
+	"chat.requirements_updated":   "Thanks, I've updated your trip.",
+	"chat.requirements_complete":  "Thanks, I have everything I need. Send me a message when you're ready to see flights.",
+	"chat.review_continues":       "No problem, take your time reviewing your trip.",
+	"chat.message_blocked":        "Sorry, I can't help with that message. Could you tell me about your trip instead?",
+	"chat.message_too_long":       "Your message is too long. Please keep it under %d characters.",
+
+	"booking.flight":        "flight",
+	"booking.accommodation": "accommodation",
+
+	"progress.parsing_requirements":   "parsing requirements",
+	"progress.searching_flights":      "searching flights",
+	"progress.choosing_flight":        "choosing flight for %s mode",
+	"progress.planning_trip":          "planning %s trip",
+	"progress.choosing_accommodation": "choosing accommodation",
+
+	"mode.chill":    "chill",
+	"mode.moderate": "moderate",
+	"mode.intense":  "intense",
+
+	"stage.collecting_requirements": "collecting requirements",
+	"stage.choosing_flight":         "choosing flight",
+	"stage.choosing_accommodation":  "choosing accommodation",
+	"stage.reviewing":               "reviewing",
+	"stage.confirmed":               "confirmed",
+
+	"list.separator":      ", ",
+	"list.last_separator": " and ",
+
+	"weekday.sunday":    "Sunday",
+	"weekday.monday":    "Monday",
+	"weekday.tuesday":   "Tuesday",
+	"weekday.wednesday": "Wednesday",
+	"weekday.thursday":  "Thursday",
+	"weekday.friday":    "Friday",
+	"weekday.saturday":  "Saturday",
+
+	"message.accommodation_retrieved":           "Accommodation retrieved successfully",
+	"message.accommodation_search_completed":    "Accommodation search completed successfully",
+	"message.accommodations_retrieved":          "Accommodations retrieved successfully",
+	"message.accommodations_retrieved_for_city": "Accommodations retrieved successfully for %s",
+	"message.flight_retrieved":                  "Flight retrieved successfully",
+	"message.flight_search_completed":           "Flight search completed successfully",
+	"message.flights_retrieved":                 "Flights retrieved successfully",
+
+	"error.accommodation_agent_not_configured":         "No accommodation agent is configured",
+	"error.accommodation_not_found":                    "Accommodation not found",
+	"error.cannot_move_back":                           "Cannot move back from stage %s",
+	"error.cannot_select_a_flight_now":                 "Cannot select a flight now",
+	"error.cannot_select_an_accommodation_now":         "Cannot select an accommodation now",
+	"error.conversation_not_found":                     "Conversation not found",
+	"error.daily_quota_exceeded":                       "You have reached your daily limit of %d agent calls, please try again tomorrow",
+	"error.failed_to_apply_changes":                    "Failed to apply changes",
+	"error.failed_to_create_accommodation_booking":     "Failed to create accommodation booking",
+	"error.failed_to_create_chat_history":              "Failed to create chat history",
+	"error.failed_to_create_chat_response":             "Failed to create chat response",
+	"error.failed_to_create_flight_booking":            "Failed to create flight booking",
+	"error.failed_to_create_token":                     "Failed to create token",
+	"error.failed_to_create_trip":                      "Failed to create trip",
+	"error.failed_to_create_user":                      "Failed to create user",
+	"error.failed_to_find_accommodation":               "Failed to find accommodation",
+	"error.failed_to_find_accommodation_booking":       "Failed to find accommodation booking",
+	"error.failed_to_find_feedback":                    "Failed to find feedback",
+	"error.failed_to_find_flight":                      "Failed to find flight",
+	"error.failed_to_find_flight_booking":              "Failed to find flight booking",
+	"error.failed_to_find_itineraries":                 "Failed to find itineraries",
+	"error.failed_to_find_message":                     "Failed to find message",
+	"error.failed_to_find_pending_changes":             "Failed to find pending changes",
+	"error.failed_to_find_trace":                       "Failed to find trace",
+	"error.failed_to_find_trip":                        "Failed to find trip",
+	"error.failed_to_find_trips":                       "Failed to find trips",
+	"error.failed_to_find_usage":                       "Failed to find usage",
+	"error.failed_to_find_user":                        "Failed to find user",
+	"error.failed_to_get_accommodation_response":       "Failed to get accommodation response",
+	"error.failed_to_get_accommodations":               "Failed to get accommodations",
+	"error.failed_to_get_flight_response":              "Failed to get flight response",
+	"error.failed_to_get_flights":                      "Failed to get flights",
+	"error.failed_to_get_requirements":                 "Failed to get requirements",
+	"error.failed_to_hash_password":                    "Failed to hash password",
+	"error.failed_to_load_chat_history":                "Failed to load chat history",
+	"error.failed_to_marshal_response":                 "Failed to marshal response",
+	"error.failed_to_read_request_body":                "Failed to read request body",
+	"error.failed_to_reject_changes":                   "Failed to reject changes",
+	"error.failed_to_reserve_idempotency_key":          "Failed to reserve idempotency key",
+	"error.failed_to_resolve_stage":                    "Failed to resolve stage",
+	"error.failed_to_retrieve_accommodations":          "Failed to retrieve accommodations",
+	"error.failed_to_retrieve_accommodations_for_city": "Failed to retrieve accommodations for city",
+	"error.failed_to_retrieve_flights":                 "Failed to retrieve flights",
+	"error.failed_to_save_feedback":                    "Failed to save feedback",
+	"error.failed_to_search_accommodations":            "Failed to search accommodations",
+	"error.failed_to_search_flights":                   "Failed to search flights",
+	"error.failed_to_update_stage":                     "Failed to update stage",
+	"error.failed_to_update_trip":                      "Failed to update trip",
+	"error.failed_to_update_user":                      "Failed to update user",
+	"error.flight_not_found":                           "Flight not found",
+	"error.idempotency_key_in_progress":                "A request with this %s is already in progress",
+	"error.idempotency_key_reused":                     "%s was already used for a different request",
+	"error.idempotency_key_too_long":                   "%s must be at most %d characters",
+	"error.invalid_cursor":                             "Invalid cursor",
+	"error.invalid_email_format":                       "Invalid email format",
+	"error.invalid_password":                           "Invalid password",
+	"error.invalid_query_parameters":                   "Invalid query parameters",
+	"error.invalid_search_parameters":                  "Invalid search parameters",
+	"error.invalid_token":                              "Invalid token",
+	"error.invalid_user_id":                            "Invalid user ID in token",
+	"error.message_not_found":                          "Message not found",
+	"error.mode_not_offered":                           "No trip plan was offered for mode %s",
+	"error.no_itinerary":                               "No itinerary has been planned for this trip yet",
+	"error.no_pending_changes_to_confirm":              "No pending changes to confirm",
+	"error.no_response_generated":                      "No response generated",
+	"error.not_agent_message":                          "Only agent messages can be rated",
+	"error.not_user_message":                           "Only user messages can be edited",
+	"error.nothing_to_confirm":                         "There is nothing to confirm",
+	"error.nothing_to_regenerate":                      "There is no message to regenerate",
+	"error.password_too_short":                         "Password must be at least 8 characters long",
+	"error.trip_confirmed":                             "A confirmed trip cannot be changed",
+	"error.trip_not_found":                             "Trip not found",
+	"error.trip_not_under_review":                      "Only a trip under review can be confirmed",
+	"error.unauthorized":                               "Unauthorized",
+	"error.unsupported_locale":                         "Unsupported locale %q, expected one of %s",
+	"error.user_not_found":                             "User not found",
+	"error.validation_failed":                          "Validation failed",
+}
@@ -0,0 +1,96 @@
+// Package i18n holds the catalogs of every text the backend writes for
+// users, chat messages and error responses alike, and works out which
+// locale a request should be answered in.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Supported locales. English is the default and fills in any message
+// missing from another catalog.
+const (
+	English = "en"
+	Chinese = "zh"
+
+	Default = English
+)
+
+var catalogs = map[string]map[string]string{
+	English: en,
+	Chinese: zh,
+}
+
+// Locales returns the supported locales.
+func Locales() []string {
+	return []string{English, Chinese}
+}
+
+// Match returns the supported locale of a language tag such as "zh-CN" or
+// "en_GB", or "" if the language is not supported.
+func Match(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	return ""
+}
+
+// MatchAcceptLanguage returns the supported locale the client prefers most
+// in an Accept-Language header, or "" if it accepts none of them.
+func MatchAcceptLanguage(header string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if _, err := fmt.Sscanf(q, "%g", &quality); err != nil {
+				continue
+			}
+		}
+		if locale := Match(tag); locale != "" && quality > bestQuality {
+			best, bestQuality = locale, quality
+		}
+	}
+	return best
+}
+
+// Translate returns the message with the given key in locale, formatted
+// with args. A key missing from every catalog is returned as is.
+func Translate(locale, key string, args ...any) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		if message, ok = catalogs[Default][key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// List joins items the way locale writes a list: "a, b and c" in English.
+func List(locale string, items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	head := strings.Join(items[:len(items)-1], Translate(locale, "list.separator"))
+	return head + Translate(locale, "list.last_separator") + items[len(items)-1]
+}
+
+// FormatDate writes a date in full, e.g. "Monday, January 2, 2006" in
+// English.
+func FormatDate(locale string, t time.Time) string {
+	switch locale {
+	case Chinese:
+		return fmt.Sprintf("%d年%d月%d日 %s", t.Year(), t.Month(), t.Day(), Translate(locale, "weekday."+strings.ToLower(t.Weekday().String())))
+	default:
+		return t.Format("Monday, January 2, 2006")
+	}
+}
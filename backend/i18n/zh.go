@@ -0,0 +1,135 @@
+package i18n
+
+// zh is the Simplified Chinese catalog.
+var zh = map[string]string{
+	"chat.flight_selected":        "已选择航班 %s。接下来我们来预订住宿。",
+	"chat.accommodation_selected": "已选择住宿 %s，预订已确认。",
+	"chat.no_accommodation":       "我在%s没有找到任何住宿。可以换一个城市吗？",
+	"chat.reviewing":              "您的航班和住宿已预订。请查看行程，准备好后确认即可。",
+	"chat.confirmed":              "您的行程已确认，祝您旅途愉快！",
+	"chat.change":                 "%s 从 %s 改为 %s",
+	"chat.pending_changes":        "您想修改%s。这将取消您的%s预订。确定要继续吗？",
+	"chat.changes_applied":        "好的，已更新%s。我们从“%s”继续。",
+	"chat.changes_rejected":       "没问题，您的行程保持不变。",
+	"chat.requirements_updated":   "好的，已更新您的行程。",
+	"chat.requirements_complete":  "好的，我已经了解所有需要的信息。准备好查看航班时请给我发消息。",
+	"chat.review_continues":       "没问题，请慢慢查看您的行程。",
+	"chat.message_blocked":        "抱歉，我无法处理这条消息。可以和我说说您的行程吗？",
+	"chat.message_too_long":       "您的消息太长了，请控制在 %d 个字符以内。",
+
+	"booking.flight":        "航班",
+	"booking.accommodation": "住宿",
+
+	"progress.parsing_requirements":   "正在解析出行需求",
+	"progress.searching_flights":      "正在搜索航班",
+	"progress.choosing_flight":        "正在为%s模式选择航班",
+	"progress.planning_trip":          "正在规划%s行程",
+	"progress.choosing_accommodation": "正在选择住宿",
+
+	"mode.chill":    "休闲",
+	"mode.moderate": "适中",
+	"mode.intense":  "紧凑",
+
+	"stage.collecting_requirements": "收集需求",
+	"stage.choosing_flight":         "选择航班",
+	"stage.choosing_accommodation":  "选择住宿",
+	"stage.reviewing":               "审核行程",
+	"stage.confirmed":               "已确认",
+
+	"list.separator":      "、",
+	"list.last_separator": "和",
+
+	"weekday.sunday":    "星期日",
+	"weekday.monday":    "星期一",
+	"weekday.tuesday":   "星期二",
+	"weekday.wednesday": "星期三",
+	"weekday.thursday":  "星期四",
+	"weekday.friday":    "星期五",
+	"weekday.saturday":  "星期六",
+
+	"message.accommodation_retrieved":           "已获取住宿",
+	"message.accommodation_search_completed":    "住宿搜索完成",
+	"message.accommodations_retrieved":          "已获取住宿列表",
+	"message.accommodations_retrieved_for_city": "已获取%s的住宿",
+	"message.flight_retrieved":                  "已获取航班",
+	"message.flight_search_completed":           "航班搜索完成",
+	"message.flights_retrieved":                 "已获取航班列表",
+
+	"error.accommodation_agent_not_configured":         "未配置住宿推荐服务",
+	"error.accommodation_not_found":                    "未找到住宿",
+	"error.cannot_move_back":                           "无法从“%s”阶段返回",
+	"error.cannot_select_a_flight_now":                 "现在无法选择航班",
+	"error.cannot_select_an_accommodation_now":         "现在无法选择住宿",
+	"error.conversation_not_found":                     "未找到对话",
+	"error.daily_quota_exceeded":                       "您已达到每日 %d 次助手调用的上限，请明天再试",
+	"error.failed_to_apply_changes":                    "应用修改失败",
+	"error.failed_to_create_accommodation_booking":     "创建住宿预订失败",
+	"error.failed_to_create_chat_history":              "创建聊天记录失败",
+	"error.failed_to_create_chat_response":             "创建聊天回复失败",
+	"error.failed_to_create_flight_booking":            "创建航班预订失败",
+	"error.failed_to_create_token":                     "创建令牌失败",
+	"error.failed_to_create_trip":                      "创建行程失败",
+	"error.failed_to_create_user":                      "创建用户失败",
+	"error.failed_to_find_accommodation":               "查找住宿失败",
+	"error.failed_to_find_accommodation_booking":       "查找住宿预订失败",
+	"error.failed_to_find_feedback":                    "查找反馈失败",
+	"error.failed_to_find_flight":                      "查找航班失败",
+	"error.failed_to_find_flight_booking":              "查找航班预订失败",
+	"error.failed_to_find_itineraries":                 "查找行程安排失败",
+	"error.failed_to_find_message":                     "查找消息失败",
+	"error.failed_to_find_pending_changes":             "查找待确认的修改失败",
+	"error.failed_to_find_trace":                       "查找调用记录失败",
+	"error.failed_to_find_trip":                        "查找行程失败",
+	"error.failed_to_find_trips":                       "查找行程列表失败",
+	"error.failed_to_find_usage":                       "查找用量失败",
+	"error.failed_to_find_user":                        "查找用户失败",
+	"error.failed_to_get_accommodation_response":       "获取住宿建议失败",
+	"error.failed_to_get_accommodations":               "获取住宿失败",
+	"error.failed_to_get_flight_response":              "获取航班建议失败",
+	"error.failed_to_get_flights":                      "获取航班失败",
+	"error.failed_to_get_requirements":                 "解析出行需求失败",
+	"error.failed_to_hash_password":                    "密码加密失败",
+	"error.failed_to_load_chat_history":                "加载聊天记录失败",
+	"error.failed_to_marshal_response":                 "序列化回复失败",
+	"error.failed_to_read_request_body":                "读取请求内容失败",
+	"error.failed_to_reject_changes":                   "撤销修改失败",
+	"error.failed_to_reserve_idempotency_key":          "保留幂等键失败",
+	"error.failed_to_resolve_stage":                    "确定对话阶段失败",
+	"error.failed_to_retrieve_accommodations":          "获取住宿列表失败",
+	"error.failed_to_retrieve_accommodations_for_city": "获取该城市的住宿失败",
+	"error.failed_to_retrieve_flights":                 "获取航班列表失败",
+	"error.failed_to_save_feedback":                    "保存反馈失败",
+	"error.failed_to_search_accommodations":            "搜索住宿失败",
+	"error.failed_to_search_flights":                   "搜索航班失败",
+	"error.failed_to_update_stage":                     "更新对话阶段失败",
+	"error.failed_to_update_trip":                      "更新行程失败",
+	"error.failed_to_update_user":                      "更新用户失败",
+	"error.flight_not_found":                           "未找到航班",
+	"error.idempotency_key_in_progress":                "使用该 %s 的请求正在处理中",
+	"error.idempotency_key_reused":                     "%s 已用于另一个请求",
+	"error.idempotency_key_too_long":                   "%s 最多 %d 个字符",
+	"error.invalid_cursor":                             "无效的游标",
+	"error.invalid_email_format":                       "邮箱格式无效",
+	"error.invalid_password":                           "密码错误",
+	"error.invalid_query_parameters":                   "查询参数无效",
+	"error.invalid_search_parameters":                  "搜索参数无效",
+	"error.invalid_token":                              "令牌无效",
+	"error.invalid_user_id":                            "令牌中的用户 ID 无效",
+	"error.message_not_found":                          "未找到消息",
+	"error.mode_not_offered":                           "没有为%s模式提供行程方案",
+	"error.no_itinerary":                               "该行程尚未规划日程",
+	"error.no_pending_changes_to_confirm":              "没有待确认的修改",
+	"error.no_response_generated":                      "未生成回复",
+	"error.not_agent_message":                          "只能评价助手的消息",
+	"error.not_user_message":                           "只能编辑用户消息",
+	"error.nothing_to_confirm":                         "没有需要确认的内容",
+	"error.nothing_to_regenerate":                      "没有可以重新生成的消息",
+	"error.password_too_short":                         "密码长度至少为 8 个字符",
+	"error.trip_confirmed":                             "已确认的行程无法修改",
+	"error.trip_not_found":                             "未找到行程",
+	"error.trip_not_under_review":                      "只有待审核的行程才能确认",
+	"error.unauthorized":                               "未授权",
+	"error.unsupported_locale":                         "不支持的语言 %q，可选：%s",
+	"error.user_not_found":                             "未找到用户",
+	"error.validation_failed":                          "校验失败",
+}
@@ -0,0 +1,149 @@
+package lda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Agent is the set of model-backed functions the chat pipeline relies on.
+// Every call takes the shared LambdaPayload, sends the function the request
+// of the method's Kind built from it and returns the raw function response,
+// whose Body the caller decodes for its own stage.
+type Agent interface {
+	ParseRequirements(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error)
+	DecideFlight(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error)
+	PlanTrip(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error)
+	DecideAccommodation(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error)
+}
+
+// invokeFunc sends an already marshaled LambdaRequest to the named function
+// and returns the raw response payload. Transports report throttling as a
+// *ThrottledError and failures inside the function as a *FunctionError.
+type invokeFunc func(ctx context.Context, function string, payload []byte) ([]byte, error)
+
+// client implements Agent on top of any transport that can invoke a function
+// by name, so the Lambda and local backends share the envelope handling and
+// the InvokePolicy.
+type client struct {
+	invoke   invokeFunc
+	policy   InvokePolicy
+	breakers breakers
+}
+
+func newClient(invoke invokeFunc) *client {
+	return &client{invoke: invoke, policy: DefaultInvokePolicy()}
+}
+
+func (a *client) ParseRequirements(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	return a.call(ctx, *PARSER, KindParseRequirements, payload)
+}
+
+func (a *client) DecideFlight(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	return a.call(ctx, *FLIGHT, KindDecideFlight, payload)
+}
+
+func (a *client) PlanTrip(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	return a.call(ctx, *PLANNER, KindPlanTrip, payload)
+}
+
+func (a *client) DecideAccommodation(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	return a.call(ctx, *ACCOMMODATION, KindDecideAccommodation, payload)
+}
+
+// call sends the request of a kind built from payload and checks that the
+// answer follows the contract of that kind. What the call cost is added to
+// the context's Trace whether or not it succeeded.
+func (a *client) call(ctx context.Context, function string, kind Kind, payload LambdaPayload) (*LambdaResponse, error) {
+	marshaledPayload, err := EncodeRequest(function, kind, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker := a.breakers.get(function)
+	if retryAfter, ok := breaker.allow(&a.policy, time.Now()); !ok {
+		return nil, &CircuitOpenError{Function: function, RetryAfter: retryAfter}
+	}
+	traceFrom(ctx).addCall(AgentCall{Function: function, Kind: kind, Payload: payload})
+
+	usage := &Usage{Function: function, Kind: kind, RequestBytes: len(marshaledPayload)}
+	started := time.Now()
+	lambdaResp, err := a.callWithRetry(withUsage(ctx, usage), function, marshaledPayload)
+	usage.Duration = time.Since(started)
+	if err == nil {
+		usage.addTokens(lambdaResp.Body)
+		err = checkResponse(function, kind, payload, lambdaResp)
+	}
+	usage.Failed = err != nil
+	traceFrom(ctx).addUsage(*usage)
+	breaker.record(ctx, &a.policy, err, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return lambdaResp, nil
+}
+
+// callWithRetry repeats an attempt while the function is throttled.
+func (a *client) callWithRetry(ctx context.Context, function string, payload []byte) (*LambdaResponse, error) {
+	for attempt := 1; ; attempt++ {
+		lambdaResp, err := a.attempt(ctx, function, payload)
+
+		var throttled *ThrottledError
+		if !errors.As(err, &throttled) {
+			return lambdaResp, err
+		}
+		if attempt >= a.policy.MaxAttempts {
+			throttled.Attempts = attempt
+			return nil, throttled
+		}
+
+		select {
+		case <-time.After(a.policy.backoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt invokes the function once within its timeout and checks the
+// status code of the response.
+func (a *client) attempt(ctx context.Context, function string, payload []byte) (*LambdaResponse, error) {
+	timeout := a.policy.timeout(function)
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := a.invoke(attemptCtx, function, payload)
+	if usage := usageFrom(ctx); usage != nil {
+		usage.Attempts++
+		usage.ResponseBytes += len(output)
+	}
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			return nil, &TimeoutError{Function: function, Timeout: timeout}
+		}
+		var throttled *ThrottledError
+		var functionErr *FunctionError
+		if errors.As(err, &throttled) || errors.As(err, &functionErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to invoke %s: %w", function, err)
+	}
+
+	var lambdaResp LambdaResponse
+	if err := json.Unmarshal(output, &lambdaResp); err != nil {
+		return nil, &PayloadError{Function: function, Err: err}
+	}
+
+	switch {
+	case lambdaResp.StatusCode == http.StatusTooManyRequests:
+		return nil, &ThrottledError{Function: function, Err: errors.New(lambdaResp.Body)}
+	case lambdaResp.StatusCode >= 400:
+		return nil, &FunctionError{Function: function, StatusCode: lambdaResp.StatusCode, Message: lambdaResp.Body}
+	}
+
+	return &lambdaResp, nil
+}
@@ -0,0 +1,115 @@
+package lda
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ResponseCache keeps function responses in memory by content address, see
+// PayloadKey. Entries expire after the TTL they were stored with; when the
+// cache is full the entry closest to expiry makes room.
+type ResponseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	response LambdaResponse
+	expires  time.Time
+}
+
+func NewResponseCache(maxEntries int) *ResponseCache {
+	return &ResponseCache{maxEntries: maxEntries, entries: map[string]cacheEntry{}}
+}
+
+// Get returns a copy of the response stored under key, if it has not
+// expired.
+func (c *ResponseCache) Get(key string) (*LambdaResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	response := entry.response
+	return &response, true
+}
+
+// Put stores a copy of response under key for ttl. A ttl of zero or less
+// stores nothing.
+func (c *ResponseCache) Put(key string, response *LambdaResponse, ttl time.Duration) {
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldest)
+		}
+	}
+
+	c.entries[key] = cacheEntry{response: *response, expires: now.Add(ttl)}
+}
+
+// PayloadKey returns the content address of calling function with payload:
+// a hash of the function name and the payload, with every field holding
+// JSON re-encoded canonically so formatting and key order do not matter.
+func PayloadKey(function string, payload LambdaPayload) string {
+	v := reflect.ValueOf(&payload).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.Kind() == reflect.String {
+			field.SetString(canonicalJSON(field.String()))
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(function + "\n"))
+	json.NewEncoder(hash).Encode(payload)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// canonicalJSON re-encodes s with sorted keys and no insignificant
+// whitespace, or returns it unchanged if it is not JSON.
+func canonicalJSON(s string) string {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return s
+	}
+	if _, ok := value.(string); ok {
+		return s
+	}
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return s
+	}
+	return string(canonical)
+}
@@ -0,0 +1,513 @@
+package lda
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// The agent contract is versioned so that the backend and the functions can
+// be deployed independently. Every request names its kind and the version it
+// was written in:
+//
+//	{"body": {"schema_version": 2, "kind": "decide_flight", "mode": "chill", ...}}
+//
+// and every answer says which version it was written in:
+//
+//	{"statusCode": 200, "body": "{\"schema_version\": 2, \"selected_flight\": ...}"}
+//
+// Answers may also report the tokens their model used as "usage", see
+// TokenUsage.
+//
+// Version 1 is the flat LambdaPayload with no schema_version. Until every
+// deployed function reads version 2, requests are written over the version 1
+// payload so older functions still find their fields, and answers without a
+// schema_version are read as version 1, which has the same shape.
+const (
+	SchemaVersion       = 2
+	LegacySchemaVersion = 1
+)
+
+// Kind names what a request asks for. Function names are configurable per
+// deployment, so the function name alone does not say.
+type Kind string
+
+const (
+	KindParseRequirements   Kind = "parse_requirements"
+	KindDecideFlight        Kind = "decide_flight"
+	KindPlanTrip            Kind = "plan_trip"
+	KindDecideAccommodation Kind = "decide_accommodation"
+)
+
+// Header starts every request.
+type Header struct {
+	SchemaVersion int  `json:"schema_version"`
+	Kind          Kind `json:"kind"`
+}
+
+// Conversation describes the user and the chat so far.
+type Conversation struct {
+	UserPrompt      string `json:"user_prompt"`
+	FirstName       string `json:"first_name"`
+	Today           string `json:"today"`
+	UserCountry     string `json:"user_country"`
+	Locale          string `json:"locale"`
+	ExistingContext string `json:"existing_context"`
+	ChatHistory     string `json:"chat_history"`
+}
+
+// Repair is set when asking a function to repair an answer that failed
+// validation.
+type Repair struct {
+	PreviousResponse string   `json:"previous_response,omitempty"`
+	ValidationErrors []string `json:"validation_errors,omitempty"`
+}
+
+// ToolUse is set when the function may call backend tools, see RunTools.
+type ToolUse struct {
+	Tools     []ToolSpec `json:"tools,omitempty"`
+	ToolSteps []ToolStep `json:"tool_steps,omitempty"`
+}
+
+// ParseRequest asks the parser to merge the user's message into the trip.
+type ParseRequest struct {
+	Header
+	Conversation
+	Repair
+}
+
+// FlightRequest asks the flight decider for the best flight in one mode,
+// either from FlightDetails or by searching with tools.
+type FlightRequest struct {
+	Header
+	Locale          string `json:"locale"`
+	Mode            string `json:"mode"`
+	TripPreferences string `json:"trip_preferences"`
+	FlightDetails   string `json:"flight_details,omitempty"`
+	Repair
+	ToolUse
+}
+
+// PlanRequest asks the planner for the itinerary of one mode around the
+// selected flight.
+type PlanRequest struct {
+	Header
+	Locale          string `json:"locale"`
+	Mode            string `json:"mode"`
+	TripPreferences string `json:"trip_preferences"`
+	FlightDetails   string `json:"flight_details,omitempty"`
+	SelectedFlight  string `json:"selected_flight"`
+	Repair
+}
+
+// AccommodationRequest asks for accommodation recommendations, either from
+// AccommodationOptions or by searching with tools.
+type AccommodationRequest struct {
+	Header
+	Conversation
+	AccommodationOptions string `json:"accommodation_options,omitempty"`
+	CheckInDate          string `json:"check_in_date"`
+	CheckOutDate         string `json:"check_out_date"`
+	Guests               int    `json:"guests"`
+	Repair
+	ToolUse
+}
+
+// TextResponse answers parse, plan and accommodation requests. Response
+// holds the model's answer, with the JSON the caller decodes inside it.
+type TextResponse struct {
+	SchemaVersion int         `json:"schema_version,omitempty"`
+	Response      string      `json:"response"`
+	Usage         *TokenUsage `json:"usage,omitempty"`
+}
+
+// FlightResponse answers flight requests.
+type FlightResponse struct {
+	SchemaVersion   int         `json:"schema_version,omitempty"`
+	SelectedFlight  string      `json:"selected_flight"`
+	TripPreferences *string     `json:"trip_preferences"`
+	Mode            string      `json:"mode"`
+	Usage           *TokenUsage `json:"usage,omitempty"`
+}
+
+// ToolCallsResponse answers a request offering tools with the calls the
+// function wants made before it answers.
+type ToolCallsResponse struct {
+	SchemaVersion int         `json:"schema_version,omitempty"`
+	ToolCalls     []ToolCall  `json:"tool_calls"`
+	Usage         *TokenUsage `json:"usage,omitempty"`
+}
+
+// Request is a request of any kind.
+type Request interface {
+	// Validate returns one message per missing or inconsistent field.
+	Validate() []string
+	// Payload returns the request as a LambdaPayload.
+	Payload() LambdaPayload
+}
+
+func conversationOf(p LambdaPayload) Conversation {
+	return Conversation{
+		UserPrompt:      p.UserPrompt,
+		FirstName:       p.FirstName,
+		Today:           p.Today,
+		UserCountry:     p.UserCountry,
+		Locale:          p.Locale,
+		ExistingContext: p.ExistingContext,
+		ChatHistory:     p.ChatHistory,
+	}
+}
+
+func (c Conversation) fill(p *LambdaPayload) {
+	p.UserPrompt = c.UserPrompt
+	p.FirstName = c.FirstName
+	p.Today = c.Today
+	p.UserCountry = c.UserCountry
+	p.Locale = c.Locale
+	p.ExistingContext = c.ExistingContext
+	p.ChatHistory = c.ChatHistory
+}
+
+func repairOf(p LambdaPayload) Repair {
+	return Repair{PreviousResponse: p.PreviousResponse, ValidationErrors: p.ValidationErrors}
+}
+
+func (r Repair) fill(p *LambdaPayload) {
+	p.PreviousResponse = r.PreviousResponse
+	p.ValidationErrors = r.ValidationErrors
+}
+
+func toolUseOf(p LambdaPayload) ToolUse {
+	return ToolUse{Tools: p.Tools, ToolSteps: p.ToolSteps}
+}
+
+func (t ToolUse) fill(p *LambdaPayload) {
+	p.Tools = t.Tools
+	p.ToolSteps = t.ToolSteps
+}
+
+func (r *ParseRequest) Validate() []string {
+	return required(map[string]string{
+		"today":            r.Today,
+		"existing_context": r.ExistingContext,
+	})
+}
+
+func (r *ParseRequest) Payload() LambdaPayload {
+	var p LambdaPayload
+	r.Conversation.fill(&p)
+	r.Repair.fill(&p)
+	return p
+}
+
+func (r *FlightRequest) Validate() []string {
+	errs := required(map[string]string{
+		"mode":             r.Mode,
+		"trip_preferences": r.TripPreferences,
+	})
+	if r.FlightDetails == "" && len(r.Tools) == 0 {
+		errs = append(errs, "flight_details: is required when no tools are offered")
+	}
+	return errs
+}
+
+func (r *FlightRequest) Payload() LambdaPayload {
+	p := LambdaPayload{
+		Locale:          r.Locale,
+		Mode:            r.Mode,
+		TripPreferences: r.TripPreferences,
+		FlightDetails:   r.FlightDetails,
+	}
+	r.Repair.fill(&p)
+	r.ToolUse.fill(&p)
+	return p
+}
+
+func (r *PlanRequest) Validate() []string {
+	return required(map[string]string{
+		"mode":             r.Mode,
+		"trip_preferences": r.TripPreferences,
+		"selected_flight":  r.SelectedFlight,
+	})
+}
+
+func (r *PlanRequest) Payload() LambdaPayload {
+	p := LambdaPayload{
+		Locale:          r.Locale,
+		Mode:            r.Mode,
+		TripPreferences: r.TripPreferences,
+		FlightDetails:   r.FlightDetails,
+		SelectedFlight:  r.SelectedFlight,
+	}
+	r.Repair.fill(&p)
+	return p
+}
+
+func (r *AccommodationRequest) Validate() []string {
+	errs := required(map[string]string{
+		"today":            r.Today,
+		"existing_context": r.ExistingContext,
+		"check_in_date":    r.CheckInDate,
+		"check_out_date":   r.CheckOutDate,
+	})
+	if r.AccommodationOptions == "" && len(r.Tools) == 0 {
+		errs = append(errs, "accommodation_options: is required when no tools are offered")
+	}
+	return errs
+}
+
+func (r *AccommodationRequest) Payload() LambdaPayload {
+	p := LambdaPayload{
+		AccommodationOptions: r.AccommodationOptions,
+		CheckInDate:          r.CheckInDate,
+		CheckOutDate:         r.CheckOutDate,
+		Guests:               r.Guests,
+	}
+	r.Conversation.fill(&p)
+	r.Repair.fill(&p)
+	r.ToolUse.fill(&p)
+	return p
+}
+
+// required returns a message for every empty field, in a stable order.
+func required(fields map[string]string) []string {
+	var errs []string
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if fields[name] == "" {
+			errs = append(errs, name+": is required")
+		}
+	}
+	return errs
+}
+
+// contract is what each kind of request is built from and answered with.
+type contract struct {
+	build    func(header Header, p LambdaPayload) Request
+	empty    func() Request
+	response *Schema
+}
+
+var usageSchema = Object(nil, map[string]*Schema{
+	"input_tokens":  Of("integer"),
+	"output_tokens": Of("integer"),
+})
+
+var textResponseSchema = Object([]string{"response"}, map[string]*Schema{
+	"schema_version": Of("integer"),
+	"response":       Of("string"),
+	"usage":          usageSchema,
+})
+
+var flightResponseSchema = Object([]string{"selected_flight"}, map[string]*Schema{
+	"schema_version":   Of("integer"),
+	"selected_flight":  Of("string"),
+	"trip_preferences": Of("string"),
+	"mode":             Of("string"),
+	"usage":            usageSchema,
+})
+
+var toolCallsResponseSchema = Object([]string{"tool_calls"}, map[string]*Schema{
+	"schema_version": Of("integer"),
+	"tool_calls": Array(Object([]string{"name"}, map[string]*Schema{
+		"id":        Of("string"),
+		"name":      Of("string"),
+		"arguments": Of("object"),
+	})),
+	"usage": usageSchema,
+})
+
+var contracts = map[Kind]contract{
+	KindParseRequirements: {
+		build: func(h Header, p LambdaPayload) Request {
+			return &ParseRequest{Header: h, Conversation: conversationOf(p), Repair: repairOf(p)}
+		},
+		empty:    func() Request { return &ParseRequest{} },
+		response: textResponseSchema,
+	},
+	KindDecideFlight: {
+		build: func(h Header, p LambdaPayload) Request {
+			return &FlightRequest{
+				Header:          h,
+				Locale:          p.Locale,
+				Mode:            p.Mode,
+				TripPreferences: p.TripPreferences,
+				FlightDetails:   p.FlightDetails,
+				Repair:          repairOf(p),
+				ToolUse:         toolUseOf(p),
+			}
+		},
+		empty:    func() Request { return &FlightRequest{} },
+		response: flightResponseSchema,
+	},
+	KindPlanTrip: {
+		build: func(h Header, p LambdaPayload) Request {
+			return &PlanRequest{
+				Header:          h,
+				Locale:          p.Locale,
+				Mode:            p.Mode,
+				TripPreferences: p.TripPreferences,
+				FlightDetails:   p.FlightDetails,
+				SelectedFlight:  p.SelectedFlight,
+				Repair:          repairOf(p),
+			}
+		},
+		empty:    func() Request { return &PlanRequest{} },
+		response: textResponseSchema,
+	},
+	KindDecideAccommodation: {
+		build: func(h Header, p LambdaPayload) Request {
+			return &AccommodationRequest{
+				Header:               h,
+				Conversation:         conversationOf(p),
+				AccommodationOptions: p.AccommodationOptions,
+				CheckInDate:          p.CheckInDate,
+				CheckOutDate:         p.CheckOutDate,
+				Guests:               p.Guests,
+				Repair:               repairOf(p),
+				ToolUse:              toolUseOf(p),
+			}
+		},
+		empty:    func() Request { return &AccommodationRequest{} },
+		response: textResponseSchema,
+	},
+}
+
+// ContractError is returned when a request or an answer does not follow the
+// contract of its kind.
+type ContractError struct {
+	Function string
+	Kind     Kind
+	// Response is set when the function's answer was at fault rather than
+	// the backend's request.
+	Response bool
+	Errors   []string
+}
+
+func (e *ContractError) Error() string {
+	what := "request"
+	if e.Response {
+		what = "response"
+	}
+	return fmt.Sprintf("invalid %s %s for %s: %s", e.Kind, what, e.Function, strings.Join(e.Errors, "; "))
+}
+
+// EncodeRequest builds the request of a kind from payload, validates it and
+// returns the LambdaRequest to send, written over the version 1 payload.
+func EncodeRequest(function string, kind Kind, payload LambdaPayload) ([]byte, error) {
+	c, ok := contracts[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown request kind %q", kind)
+	}
+	request := c.build(Header{SchemaVersion: SchemaVersion, Kind: kind}, payload)
+	if errs := request.Validate(); len(errs) > 0 {
+		return nil, &ContractError{Function: function, Kind: kind, Errors: errs}
+	}
+
+	body, err := overlay(payload, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return json.Marshal(LambdaRequest{Body: body})
+}
+
+// overlay writes request over the version 1 payload, so a function that
+// still reads version 1 finds every field under its old name.
+func overlay(legacy LambdaPayload, request Request) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	legacyJSON, err := json.Marshal(legacy)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(legacyJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]json.RawMessage{}
+	if err := json.Unmarshal(requestJSON, &current); err != nil {
+		return nil, err
+	}
+	maps.Copy(fields, current)
+
+	return json.Marshal(fields)
+}
+
+// DecodeRequest reads a LambdaRequest as a function does, returning its kind
+// and payload. Requests without a schema_version are read as version 1, whose
+// kind is unknown.
+func DecodeRequest(data []byte) (Kind, LambdaPayload, error) {
+	var request LambdaRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return "", LambdaPayload{}, fmt.Errorf("failed to parse request: %v", err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(request.Body, &header); err != nil {
+		return "", LambdaPayload{}, fmt.Errorf("failed to parse request: %v", err)
+	}
+
+	switch {
+	case header.SchemaVersion == 0 || header.SchemaVersion == LegacySchemaVersion:
+		var payload LambdaPayload
+		if err := json.Unmarshal(request.Body, &payload); err != nil {
+			return "", LambdaPayload{}, fmt.Errorf("failed to parse request: %v", err)
+		}
+		return "", payload, nil
+	case header.SchemaVersion > SchemaVersion:
+		return "", LambdaPayload{}, fmt.Errorf("unsupported schema version %d", header.SchemaVersion)
+	}
+
+	c, ok := contracts[header.Kind]
+	if !ok {
+		return "", LambdaPayload{}, fmt.Errorf("unknown request kind %q", header.Kind)
+	}
+	decoded := c.empty()
+	if err := json.Unmarshal(request.Body, decoded); err != nil {
+		return "", LambdaPayload{}, fmt.Errorf("failed to parse request: %v", err)
+	}
+	if errs := decoded.Validate(); len(errs) > 0 {
+		return "", LambdaPayload{}, fmt.Errorf("invalid %s request: %s", header.Kind, strings.Join(errs, "; "))
+	}
+	return header.Kind, decoded.Payload(), nil
+}
+
+// checkResponse validates a function's answer against the contract of the
+// request's kind. Tool calls are only accepted when tools were offered.
+func checkResponse(function string, kind Kind, payload LambdaPayload, response *LambdaResponse) error {
+	c, ok := contracts[kind]
+	if !ok {
+		return fmt.Errorf("unknown request kind %q", kind)
+	}
+	fail := func(errs ...string) error {
+		return &ContractError{Function: function, Kind: kind, Response: true, Errors: errs}
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		return fail("body is not a JSON object: " + err.Error())
+	}
+
+	version := LegacySchemaVersion
+	if raw, ok := body["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return fail("schema_version: " + err.Error())
+		}
+	}
+	if version < LegacySchemaVersion || version > SchemaVersion {
+		return fail(fmt.Sprintf("unsupported schema version %d", version))
+	}
+
+	schema := c.response
+	if _, ok := body["tool_calls"]; ok && len(payload.Tools) > 0 {
+		schema = toolCallsResponseSchema
+	}
+	if errs := schema.Validate([]byte(response.Body)); len(errs) > 0 {
+		return fail(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package lda
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned when a function does not answer within its
+// timeout.
+type TimeoutError struct {
+	Function string
+	Timeout  time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Function, e.Timeout)
+}
+
+// FunctionError is returned when a function ran but reported a failure,
+// either as an unhandled error or as an error status code in its response.
+type FunctionError struct {
+	Function   string
+	StatusCode int
+	Message    string
+}
+
+func (e *FunctionError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s failed with status %d: %s", e.Function, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s failed: %s", e.Function, e.Message)
+}
+
+// PayloadError is returned when a function's response is not a valid
+// LambdaResponse.
+type PayloadError struct {
+	Function string
+	Err      error
+}
+
+func (e *PayloadError) Error() string {
+	return fmt.Sprintf("%s returned an invalid response: %v", e.Function, e.Err)
+}
+
+func (e *PayloadError) Unwrap() error {
+	return e.Err
+}
+
+// ThrottledError is returned when a function is still throttled after
+// every retry.
+type ThrottledError struct {
+	Function string
+	Attempts int
+	Err      error
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Attempts > 0 {
+		return fmt.Sprintf("%s is throttled after %d attempts: %v", e.Function, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%s is throttled: %v", e.Function, e.Err)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// CircuitOpenError is returned without invoking a function that has failed
+// repeatedly, until its cooldown has passed.
+type CircuitOpenError struct {
+	Function   string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s is unavailable, retry after %s", e.Function, e.RetryAfter.Round(time.Second))
+}
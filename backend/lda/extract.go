@@ -0,0 +1,83 @@
+package lda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var fencePattern = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)```")
+
+// ExtractJSON finds the JSON object in a model's answer. It accepts a bare
+// object, one inside a code fence of any language tag, or one surrounded by
+// prose, and returns the first candidate that parses.
+func ExtractJSON(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if isJSONObject(text) {
+		return []byte(text), nil
+	}
+
+	for _, match := range fencePattern.FindAllStringSubmatch(text, -1) {
+		if candidate := strings.TrimSpace(match[1]); isJSONObject(candidate) {
+			return []byte(candidate), nil
+		}
+	}
+
+	// Fall back to the first '{' from which a complete object decodes,
+	// ignoring whatever follows it.
+	for i := strings.IndexByte(text, '{'); i >= 0; {
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw); err == nil {
+			return raw, nil
+		}
+		next := strings.IndexByte(text[i+1:], '{')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	return nil, errors.New("no JSON object found in response")
+}
+
+func isJSONObject(text string) bool {
+	return strings.HasPrefix(text, "{") && json.Valid([]byte(text))
+}
+
+// OutputError is returned when an agent's answer still does not match the
+// expected schema after a repair attempt.
+type OutputError struct {
+	Function string
+	Errors   []string
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("%s returned invalid output: %s", e.Function, strings.Join(e.Errors, "; "))
+}
+
+// DecodeOutput extracts the JSON object from text, validates it against
+// schema and decodes it into target. It returns the problems found, if any,
+// in a form that can be sent back to the agent.
+func DecodeOutput(text string, schema *Schema, target any) []string {
+	raw, err := ExtractJSON(text)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	if errs := schema.Validate(raw); len(errs) > 0 {
+		return errs
+	}
+
+	// Start from a clean target so a failed earlier attempt leaves nothing behind.
+	reflect.ValueOf(target).Elem().SetZero()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if err := decoder.Decode(target); err != nil {
+		return []string{err.Error()}
+	}
+
+	return nil
+}
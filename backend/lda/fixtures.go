@@ -0,0 +1,168 @@
+package lda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Fixtures are agent calls saved as JSON files, one per call, at
+// <dir>/<function>/<key>.json where key is FixtureKey of the payload the
+// function received. A recording agent writes them while a real backend
+// answers; a replay agent answers from them alone, so the chat pipeline can
+// run offline and deterministically, e.g. from tests:
+//
+//	lda.SetAgent(lda.NewReplayAgent("testdata/fixtures"))
+type Fixture struct {
+	Function string         `json:"function"`
+	Request  LambdaPayload  `json:"request"`
+	Response LambdaResponse `json:"response"`
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+// FixtureKey is PayloadKey with the values that change from run to run
+// blanked out: today's date, generated IDs, timestamps and tool timings.
+// IDs and timestamps are blanked wherever they appear in the payload,
+// including inside tool steps.
+func FixtureKey(function string, payload LambdaPayload) string {
+	payload.Today = ""
+	payload.ToolSteps = append([]ToolStep(nil), payload.ToolSteps...)
+	for i := range payload.ToolSteps {
+		payload.ToolSteps[i].DurationMs = 0
+	}
+
+	data, err := json.Marshal(payload)
+	if err == nil {
+		scrubbed := uuidPattern.ReplaceAll(data, []byte("<id>"))
+		scrubbed = timestampPattern.ReplaceAll(scrubbed, []byte("<time>"))
+		var clean LambdaPayload
+		if json.Unmarshal(scrubbed, &clean) == nil {
+			payload = clean
+		}
+	}
+	return PayloadKey(function, payload)
+}
+
+func fixturePath(dir, function string, payload LambdaPayload) string {
+	return filepath.Join(dir, function, FixtureKey(function, payload)+".json")
+}
+
+// requestPayload returns the payload a function receives for a call of a
+// kind, which is what EncodeRequest keeps of it. Fixtures are saved under
+// it, as a replay only sees the request.
+func requestPayload(function string, kind Kind, payload LambdaPayload) (LambdaPayload, error) {
+	request, err := EncodeRequest(function, kind, payload)
+	if err != nil {
+		return LambdaPayload{}, err
+	}
+	_, received, err := DecodeRequest(request)
+	return received, err
+}
+
+// recordingAgent saves every successful call of the wrapped agent.
+type recordingAgent struct {
+	agent Agent
+	dir   string
+}
+
+// NewRecordingAgent returns an Agent that forwards every call to agent and
+// saves each successful call as a fixture under dir.
+func NewRecordingAgent(agent Agent, dir string) Agent {
+	return &recordingAgent{agent: agent, dir: dir}
+}
+
+func (a *recordingAgent) ParseRequirements(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	response, err := a.agent.ParseRequirements(ctx, payload)
+	return a.record(*PARSER, KindParseRequirements, payload, response, err)
+}
+
+func (a *recordingAgent) DecideFlight(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	response, err := a.agent.DecideFlight(ctx, payload)
+	return a.record(*FLIGHT, KindDecideFlight, payload, response, err)
+}
+
+func (a *recordingAgent) PlanTrip(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	response, err := a.agent.PlanTrip(ctx, payload)
+	return a.record(*PLANNER, KindPlanTrip, payload, response, err)
+}
+
+func (a *recordingAgent) DecideAccommodation(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error) {
+	response, err := a.agent.DecideAccommodation(ctx, payload)
+	return a.record(*ACCOMMODATION, KindDecideAccommodation, payload, response, err)
+}
+
+func (a *recordingAgent) record(function string, kind Kind, payload LambdaPayload, response *LambdaResponse, err error) (*LambdaResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err = requestPayload(function, kind, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build fixture request: %v", err)
+	}
+	fixture, err := json.MarshalIndent(Fixture{Function: function, Request: payload, Response: *response}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fixture: %v", err)
+	}
+
+	path := fixturePath(a.dir, function, payload)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %v", err)
+	}
+	// Write through a temporary file so a reader never sees a partial
+	// fixture.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "fixture-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write fixture: %v", err)
+	}
+	_, err = tmp.Write(fixture)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to write fixture: %v", err)
+	}
+
+	return response, nil
+}
+
+// NewReplayAgent returns an Agent that answers from the fixtures under dir
+// and fails any call that has not been recorded. The answers go through the
+// same contract checks and usage tracking as those of a live backend.
+func NewReplayAgent(dir string) Agent {
+	return newClient(func(ctx context.Context, function string, request []byte) ([]byte, error) {
+		_, payload, err := DecodeRequest(request)
+		if err != nil {
+			return nil, err
+		}
+
+		path := fixturePath(dir, function, payload)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no fixture for %s call at %s, record it with AGENT_RECORD", function, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %v", err)
+		}
+
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %v", path, err)
+		}
+
+		return json.Marshal(fixture.Response)
+	})
+}
@@ -6,4 +6,18 @@ var (
 	PARSER  = aws.String("data_parser")
 	FLIGHT  = aws.String("flight_decider")
 	PLANNER = aws.String("trip_planner")
+	// ACCOMMODATION is set by Init from AGENT_ACCOMMODATION_FUNCTION. It
+	// stays empty when no function is configured, and the accommodation
+	// stage refuses to run rather than ask another function.
+	ACCOMMODATION = aws.String("")
 )
+
+// stubAccommodationFunction names the accommodation decider of the stub
+// handlers when AGENT_ACCOMMODATION_FUNCTION is unset.
+const stubAccommodationFunction = "accommodation_decider"
+
+// AccommodationConfigured reports whether a function is configured to
+// recommend accommodation.
+func AccommodationConfigured() bool {
+	return *ACCOMMODATION != ""
+}
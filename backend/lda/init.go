@@ -4,32 +4,78 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
 )
 
-var lambdaClient *lambda.Client
+const (
+	BackendLambda = "lambda"
+	BackendLocal  = "local"
+	BackendReplay = "replay"
+)
+
+var agent Agent
 
-func Init(ctx context.Context, cfg aws.Config) {
-	tempLambdaClient := lambda.NewFromConfig(cfg)
-	result, err := tempLambdaClient.ListFunctions(ctx, &lambda.ListFunctionsInput{
-		MaxItems: aws.Int32(int32(10)),
-	})
-	if err != nil {
-		log.Fatal("Failed to list Lambda functions:", err)
+// Init selects the agent backend from AGENT_BACKEND ("lambda" by default,
+// "local" or "replay"). The local backend forwards to AGENT_URL when it is
+// set and otherwise answers in-process, so no AWS credentials are needed.
+// The replay backend answers from the fixtures in AGENT_FIXTURES. Setting
+// AGENT_RECORD to a directory records every call of any backend there.
+// AGENT_ACCOMMODATION_FUNCTION names the accommodation decider; only the
+// stub handlers have one without it.
+func Init(ctx context.Context) error {
+	backend := os.Getenv("AGENT_BACKEND")
+	if backend == "" {
+		backend = BackendLambda
+	}
+
+	accommodation := os.Getenv("AGENT_ACCOMMODATION_FUNCTION")
+	if accommodation == "" && backend == BackendLocal && os.Getenv("AGENT_URL") == "" {
+		accommodation = stubAccommodationFunction
+	}
+	ACCOMMODATION = aws.String(accommodation)
+	if accommodation == "" {
+		log.Println("Warning: AGENT_ACCOMMODATION_FUNCTION is not set, accommodation cannot be chosen")
 	}
 
-	for _, fun := range result.Functions {
-		fmt.Println("Lambda Functions:", *fun.FunctionName)
+	switch backend {
+	case BackendLambda:
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to load AWS SDK config: %v", err)
+		}
+		agent = NewLambdaAgent(ctx, cfg)
+	case BackendLocal:
+		agent = NewLocalAgent(os.Getenv("AGENT_URL"))
+	case BackendReplay:
+		dir := os.Getenv("AGENT_FIXTURES")
+		if dir == "" {
+			return fmt.Errorf("AGENT_FIXTURES must be set for the %s backend", BackendReplay)
+		}
+		agent = NewReplayAgent(dir)
+	default:
+		return fmt.Errorf("unknown AGENT_BACKEND %q", backend)
 	}
 
-	lambdaClient = tempLambdaClient
+	if dir := os.Getenv("AGENT_RECORD"); dir != "" {
+		agent = NewRecordingAgent(agent, dir)
+		log.Println("Recording agent calls to", dir)
+	}
+
+	log.Println("Agent backend:", backend)
+	return nil
+}
+
+// SetAgent replaces the active agent, e.g. with a stub in tools and tests.
+func SetAgent(a Agent) {
+	agent = a
 }
 
-func GetLambda() *lambda.Client {
-	if lambdaClient == nil {
-		log.Panic("Lambda client not initialized. Call Init first.")
+func GetAgent() Agent {
+	if agent == nil {
+		log.Panic("Agent not initialized. Call Init first.")
 	}
-	return lambdaClient
+	return agent
 }
@@ -0,0 +1,152 @@
+package lda
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InvokePolicy controls how every agent function is invoked: how long a
+// single attempt may take, how throttled calls are retried and when a
+// failing function stops being called for a while.
+type InvokePolicy struct {
+	// Timeouts holds the per-attempt timeout of each function by name.
+	// A function can be overridden with AGENT_TIMEOUT_<NAME>, e.g.
+	// AGENT_TIMEOUT_TRIP_PLANNER=2m.
+	Timeouts       map[string]time.Duration
+	DefaultTimeout time.Duration
+
+	// Throttled calls are retried up to MaxAttempts in total, waiting an
+	// exponentially growing, jittered delay between BaseBackoff and
+	// MaxBackoff.
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// After FailureThreshold consecutive failures a function's circuit
+	// opens and calls fail fast for Cooldown, after which a single trial
+	// call decides whether it closes again.
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// DefaultInvokePolicy returns the policy used by the built-in agents.
+func DefaultInvokePolicy() InvokePolicy {
+	return InvokePolicy{
+		Timeouts: map[string]time.Duration{
+			*PARSER:  30 * time.Second,
+			*FLIGHT:  60 * time.Second,
+			*PLANNER: 90 * time.Second,
+		},
+		DefaultTimeout:   60 * time.Second,
+		MaxAttempts:      3,
+		BaseBackoff:      250 * time.Millisecond,
+		MaxBackoff:       4 * time.Second,
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+func (p *InvokePolicy) timeout(function string) time.Duration {
+	env := "AGENT_TIMEOUT_" + strings.ToUpper(strings.ReplaceAll(function, "-", "_"))
+	if timeout, err := time.ParseDuration(os.Getenv(env)); err == nil && timeout > 0 {
+		return timeout
+	}
+	if timeout, ok := p.Timeouts[function]; ok {
+		return timeout
+	}
+	return p.DefaultTimeout
+}
+
+// backoff returns the delay before the given retry, counting from 1: half
+// of the exponential delay plus a random share of the other half.
+func (p *InvokePolicy) backoff(retry int) time.Duration {
+	delay := p.BaseBackoff << (retry - 1)
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// breaker is the circuit breaker of a single function.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go ahead and, if not, how long until it
+// may be tried again. Once the cooldown has passed only one trial call is
+// let through at a time.
+func (b *breaker) allow(policy *InvokePolicy, now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < policy.FailureThreshold {
+		return 0, true
+	}
+	if now.Before(b.openUntil) {
+		return b.openUntil.Sub(now), false
+	}
+	if b.probing {
+		return policy.Cooldown, false
+	}
+	b.probing = true
+	return 0, true
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *breaker) record(ctx context.Context, policy *InvokePolicy, err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch {
+	case err == nil:
+		b.failures = 0
+	case countsAsFailure(ctx, err):
+		b.failures++
+		if b.failures >= policy.FailureThreshold {
+			b.openUntil = now.Add(policy.Cooldown)
+		}
+	}
+}
+
+// countsAsFailure reports whether err says something about the health of
+// the function. Calls abandoned by the caller and requests the function
+// rejected as invalid do not.
+func countsAsFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var functionErr *FunctionError
+	if errors.As(err, &functionErr) && functionErr.StatusCode >= 400 && functionErr.StatusCode < 500 {
+		return false
+	}
+	return true
+}
+
+// breakers holds one breaker per function name.
+type breakers struct {
+	mu     sync.Mutex
+	byName map[string]*breaker
+}
+
+func (b *breakers) get(function string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.byName == nil {
+		b.byName = map[string]*breaker{}
+	}
+	if _, ok := b.byName[function]; !ok {
+		b.byName[function] = &breaker{}
+	}
+	return b.byName[function]
+}
@@ -0,0 +1,68 @@
+package lda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// NewLambdaAgent returns an Agent backed by the deployed AWS Lambda
+// functions. Listing the functions is only a startup diagnostic; failing to
+// do so is logged rather than fatal.
+func NewLambdaAgent(ctx context.Context, cfg aws.Config) Agent {
+	// Retries are left to the client's InvokePolicy.
+	lambdaClient := lambda.NewFromConfig(cfg, func(o *lambda.Options) {
+		o.Retryer = aws.NopRetryer{}
+	})
+	result, err := lambdaClient.ListFunctions(ctx, &lambda.ListFunctionsInput{
+		MaxItems: aws.Int32(int32(10)),
+	})
+	if err != nil {
+		log.Println("Warning: failed to list Lambda functions:", err)
+	} else {
+		for _, fun := range result.Functions {
+			fmt.Println("Lambda Functions:", *fun.FunctionName)
+		}
+	}
+
+	return newClient(func(ctx context.Context, function string, payload []byte) ([]byte, error) {
+		output, err := lambdaClient.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName: aws.String(function),
+			Payload:      payload,
+			LogType:      types.LogTypeTail,
+		})
+		if err != nil {
+			var tooManyRequests *types.TooManyRequestsException
+			var ec2Throttled *types.EC2ThrottledException
+			if errors.As(err, &tooManyRequests) || errors.As(err, &ec2Throttled) {
+				return nil, &ThrottledError{Function: function, Err: err}
+			}
+			return nil, err
+		}
+		if usage := usageFrom(ctx); usage != nil && output.LogResult != nil {
+			usage.BilledDuration += billedDuration(*output.LogResult)
+		}
+
+		// An unhandled error inside the function still returns 200, with
+		// the error described in the payload.
+		if output.FunctionError != nil {
+			var lambdaErr struct {
+				ErrorMessage string `json:"errorMessage"`
+				ErrorType    string `json:"errorType"`
+			}
+			message := string(output.Payload)
+			if json.Unmarshal(output.Payload, &lambdaErr) == nil && lambdaErr.ErrorMessage != "" {
+				message = lambdaErr.ErrorType + ": " + lambdaErr.ErrorMessage
+			}
+			return nil, &FunctionError{Function: function, Message: message}
+		}
+
+		return output.Payload, nil
+	})
+}
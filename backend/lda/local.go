@@ -0,0 +1,82 @@
+package lda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HandlerFunc answers one agent function in-process and returns the
+// response body the deployed function would have produced.
+type HandlerFunc func(ctx context.Context, payload LambdaPayload) (string, error)
+
+// NewLocalAgent returns an Agent that runs without AWS. With a baseURL, every
+// function is POSTed as a LambdaRequest to baseURL/<function name> and the
+// reply is read as a LambdaResponse, which suits running the Python functions
+// behind a local HTTP server. Without one, the built-in stub handlers answer.
+func NewLocalAgent(baseURL string) Agent {
+	if baseURL == "" {
+		return NewInProcessAgent(StubHandlers())
+	}
+
+	// Timeouts come from the client's InvokePolicy.
+	httpClient := &http.Client{}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return newClient(func(ctx context.Context, function string, payload []byte) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/"+function, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusTooManyRequests {
+			return nil, &ThrottledError{Function: function, Err: fmt.Errorf("local agent returned %s", res.Status)}
+		}
+		if res.StatusCode >= 300 {
+			return nil, &FunctionError{Function: function, StatusCode: res.StatusCode, Message: string(body)}
+		}
+		return body, nil
+	})
+}
+
+// NewInProcessAgent returns an Agent whose functions are answered by the
+// given handlers, keyed by function name.
+func NewInProcessAgent(handlers map[string]HandlerFunc) Agent {
+	return newClient(func(ctx context.Context, function string, payload []byte) ([]byte, error) {
+		handler, ok := handlers[function]
+		if !ok {
+			return nil, fmt.Errorf("no local handler for function %s", function)
+		}
+
+		_, request, err := DecodeRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := handler(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(LambdaResponse{
+			Body:       body,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			StatusCode: http.StatusOK,
+		})
+	})
+}
@@ -0,0 +1,43 @@
+package lda
+
+import "encoding/json"
+
+// LambdaPayload is everything any function may be asked with, as the backend
+// builds it. It is also the version 1 request, see SchemaVersion; functions
+// receive the request of their kind built from it.
+type LambdaPayload struct {
+	UserPrompt  string `json:"user_prompt"`
+	FirstName   string `json:"first_name"`
+	Today       string `json:"today"`
+	UserCountry string `json:"user_country"`
+	// Locale is the language the user is answered in, e.g. "en" or "zh".
+	Locale               string `json:"locale,omitempty"`
+	ExistingContext      string `json:"existing_context"`
+	ChatHistory          string `json:"chat_history"`
+	FlightOptions        string `json:"flight_options,omitempty"`
+	AccommodationOptions string `json:"accomodation_options,omitempty"`
+	Mode                 string `json:"mode,omitempty"`
+	TripPreferences      string `json:"preferences,omitempty"`
+	FlightDetails        string `json:"flight_details,omitempty"`
+	SelectedFlight       string `json:"selected_flight,omitempty"`
+	CheckInDate          string `json:"check_in_date,omitempty"`
+	CheckOutDate         string `json:"check_out_date,omitempty"`
+	Guests               int    `json:"guests,omitempty"`
+	// Set when asking the function to repair an answer that failed validation.
+	PreviousResponse string   `json:"previous_response,omitempty"`
+	ValidationErrors []string `json:"validation_errors,omitempty"`
+	// Set when the function may call backend tools, see RunTools.
+	Tools     []ToolSpec `json:"tools,omitempty"`
+	ToolSteps []ToolStep `json:"tool_steps,omitempty"`
+}
+
+// LambdaRequest is the envelope every request is sent in, see EncodeRequest.
+type LambdaRequest struct {
+	Body json.RawMessage `json:"body"`
+}
+
+type LambdaResponse struct {
+	Body       string            `json:"body"`
+	Headers    map[string]string `json:"headers"`
+	StatusCode int               `json:"statusCode"`
+}
@@ -0,0 +1,153 @@
+package lda
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema needed to check agent answers: value
+// types, required properties, nested objects and array items. A null value
+// is accepted for any property that is not required. The same schemas
+// declare tool parameters, where Description tells the agent what a value
+// means.
+type Schema struct {
+	Types       []string
+	Required    []string
+	Properties  map[string]*Schema
+	Items       *Schema
+	Description string
+}
+
+// Object, Array and the scalar helpers keep schema declarations short.
+func Object(required []string, properties map[string]*Schema) *Schema {
+	return &Schema{Types: []string{"object"}, Required: required, Properties: properties}
+}
+
+func Array(items *Schema) *Schema {
+	return &Schema{Types: []string{"array"}, Items: items}
+}
+
+func Of(types ...string) *Schema {
+	return &Schema{Types: types}
+}
+
+// Describe sets the schema's description and returns the schema.
+func (s *Schema) Describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+// MarshalJSON writes the schema as standard JSON Schema.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	out := map[string]any{}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		out["type"] = s.Types[0]
+	default:
+		out["type"] = s.Types
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if s.Types != nil && s.Types[0] == "object" {
+		properties := s.Properties
+		if properties == nil {
+			properties = map[string]*Schema{}
+		}
+		out["properties"] = properties
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = s.Items
+	}
+	return json.Marshal(out)
+}
+
+// Validate checks raw JSON against the schema and returns one message per
+// violation, each prefixed with the path of the offending value.
+func (s *Schema) Validate(raw []byte) []string {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+
+	var errs []string
+	s.validate("$", value, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, value any, errs *[]string) {
+	kind := jsonType(value)
+	if !s.allows(kind, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Types, " or "), kind))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if field, ok := v[name]; !ok || field == nil {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: is required", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := v[name]; ok && field != nil {
+				s.Properties[name].validate(path+"."+name, field, errs)
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+		}
+	}
+}
+
+func (s *Schema) allows(kind string, value any) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == kind {
+			return true
+		}
+		if t == "integer" && kind == "number" {
+			if n := value.(float64); n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
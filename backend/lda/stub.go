@@ -0,0 +1,307 @@
+package lda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// StubHandlers returns deterministic stand-ins for the deployed functions.
+// The parser treats a user prompt that is a JSON object as trip fields to
+// merge, the flight decider picks the cheapest flight on offer and the
+// accommodation decider recommends the first three options, which is enough
+// to walk a conversation end to end on a laptop. When offered tools instead
+// of options, the deciders search once and choose from the results.
+func StubHandlers() map[string]HandlerFunc {
+	return map[string]HandlerFunc{
+		*PARSER:        stubParseRequirements,
+		*FLIGHT:        stubDecideFlight,
+		*PLANNER:       stubPlanTrip,
+		*ACCOMMODATION: stubDecideAccommodation,
+	}
+}
+
+func stubParseRequirements(_ context.Context, payload LambdaPayload) (string, error) {
+	tripDetails := map[string]any{}
+	if payload.ExistingContext != "" {
+		if err := json.Unmarshal([]byte(payload.ExistingContext), &tripDetails); err != nil {
+			return "", fmt.Errorf("failed to parse existing context: %v", err)
+		}
+	}
+
+	var updates map[string]any
+	if err := json.Unmarshal([]byte(payload.UserPrompt), &updates); err == nil {
+		for key, value := range updates {
+			tripDetails[key] = value
+		}
+	}
+
+	var missing []string
+	for key, value := range tripDetails {
+		// The IDs are not requirements and are never asked for.
+		if key == "trip_id" || key == "user_id" || models.OptionalTripFields[key] {
+			continue
+		}
+		if value == nil || value == "" || value == float64(0) {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	response := "Thanks, I have everything I need to look for flights."
+	if len(missing) > 0 {
+		response = "Could you tell me the following: " + strings.Join(missing, ", ") + "?"
+	}
+
+	return stubFencedResponse(map[string]any{
+		"trip_details": tripDetails,
+		"response":     response,
+	})
+}
+
+func stubDecideFlight(_ context.Context, payload LambdaPayload) (string, error) {
+	var flights, returns []models.Flights
+	if payload.FlightDetails == "" && stubOffersTool(payload, "search_flights") {
+		var result struct {
+			Flights []models.Flights `json:"flights"`
+		}
+		if !stubToolResult(payload, "search_flights", &result) {
+			var trip models.Trip
+			json.Unmarshal([]byte(payload.TripPreferences), &trip)
+			return stubToolCall(payload, "search_flights", map[string]any{
+				"start_date": stubParseDate(trip.StartDate, time.Now()).Format("2006-01-02"),
+				"end_date":   trip.EndDate,
+			})
+		}
+		flights = result.Flights
+	} else {
+		var candidates struct {
+			Outbound []models.Flights `json:"outbound"`
+			Return   []models.Flights `json:"return"`
+		}
+		if err := json.Unmarshal([]byte(payload.FlightDetails), &candidates); err == nil {
+			flights, returns = candidates.Outbound, candidates.Return
+		} else if err := json.Unmarshal([]byte(payload.FlightDetails), &flights); err != nil {
+			return "", fmt.Errorf("failed to parse flight details: %v", err)
+		}
+	}
+	if len(flights) == 0 {
+		return "", fmt.Errorf("no flights to choose from")
+	}
+
+	cheapest := stubCheapestFlight(flights)
+	price := cheapest.PriceEconomy
+	returnFlight := map[string]any{}
+	if len(returns) > 0 {
+		cheapestReturn := stubCheapestFlight(returns)
+		price += cheapestReturn.PriceEconomy
+		returnFlight = stubFlightLeg(cheapestReturn)
+	}
+
+	selected := map[string]any{
+		"selected_flight": map[string]any{
+			"airline":         cheapest.Airline,
+			"outbound_flight": stubFlightLeg(cheapest),
+			"return_flight":   returnFlight,
+			"price":           price,
+			"reason":          "Cheapest economy fare available.",
+		},
+	}
+	selectedJSON, err := json.Marshal(selected)
+	if err != nil {
+		return "", err
+	}
+
+	preferences := fmt.Sprintf("A %s paced trip.", payload.Mode)
+	body, err := json.Marshal(FlightResponse{
+		SchemaVersion:   SchemaVersion,
+		SelectedFlight:  "```json\n" + string(selectedJSON) + "\n```",
+		TripPreferences: &preferences,
+		Mode:            payload.Mode,
+	})
+	return string(body), err
+}
+
+func stubCheapestFlight(flights []models.Flights) models.Flights {
+	cheapest := flights[0]
+	for _, flight := range flights[1:] {
+		if flight.PriceEconomy < cheapest.PriceEconomy {
+			cheapest = flight
+		}
+	}
+	return cheapest
+}
+
+func stubFlightLeg(flight models.Flights) map[string]any {
+	departure := time.Time(flight.DepartureTime)
+	arrival := time.Time(flight.ArrivalTime)
+	return map[string]any{
+		"flight_number":  flight.FlightNumber,
+		"departure_city": flight.DepartureAirport,
+		"arrival_city":   flight.ArrivalAirport,
+		"departure_date": departure.Format("2006-01-02"),
+		"departure_time": departure.Format("15:04"),
+		"arrival_date":   arrival.Format("2006-01-02"),
+		"arrival_time":   arrival.Format("15:04"),
+		"duration_hours": float64(flight.DurationMinutes) / 60,
+		"stops":          []string{},
+	}
+}
+
+// stubActivitiesPerDay sets how busy a stub itinerary day is in each mode.
+var stubActivitiesPerDay = map[string]int{"chill": 2, "moderate": 3, "intense": 4}
+
+func stubPlanTrip(_ context.Context, payload LambdaPayload) (string, error) {
+	var trip models.Trip
+	if err := json.Unmarshal([]byte(payload.TripPreferences), &trip); err != nil {
+		return "", fmt.Errorf("failed to parse trip: %v", err)
+	}
+
+	start := stubParseDate(trip.StartDate, time.Now())
+	end := stubParseDate(trip.EndDate, start)
+	if end.Before(start) {
+		end = start
+	}
+	city := "your destination"
+	if len(trip.DestinationCities) > 0 {
+		city = trip.DestinationCities[0]
+	}
+	perDay := stubActivitiesPerDay[payload.Mode]
+	if perDay == 0 {
+		perDay = 3
+	}
+
+	var days []map[string]any
+	total := 0.0
+	for date, day := start, 1; !date.After(end) && day <= 14; date, day = date.AddDate(0, 0, 1), day+1 {
+		var blocks []map[string]any
+		for i := 0; i < perDay; i++ {
+			startHour := 9 + i*3
+			cost := float64(20 * (i + 1))
+			total += cost
+			blocks = append(blocks, map[string]any{
+				"start_time":     fmt.Sprintf("%02d:00", startHour),
+				"end_time":       fmt.Sprintf("%02d:00", startHour+2),
+				"activity":       fmt.Sprintf("Explore %s, stop %d", city, i+1),
+				"location":       city,
+				"estimated_cost": cost,
+			})
+		}
+		days = append(days, map[string]any{
+			"day":    day,
+			"date":   date.Format("2006-01-02"),
+			"title":  fmt.Sprintf("Day %d in %s", day, city),
+			"blocks": blocks,
+		})
+	}
+
+	return stubFencedResponse(map[string]any{
+		"summary":              fmt.Sprintf("A %s itinerary built around the selected flight.", payload.Mode),
+		"days":                 days,
+		"total_estimated_cost": total,
+		"currency":             "USD",
+	})
+}
+
+// stubParseDate accepts the date formats trips are stored with.
+func stubParseDate(date string, fallback time.Time) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+func stubDecideAccommodation(_ context.Context, payload LambdaPayload) (string, error) {
+	var accommodations []models.Accommodations
+	if payload.AccommodationOptions == "" {
+		var result struct {
+			Accommodations []models.Accommodations `json:"accommodations"`
+		}
+		if !stubToolResult(payload, "search_accommodations", &result) {
+			var trip models.Trip
+			json.Unmarshal([]byte(payload.ExistingContext), &trip)
+			arguments := map[string]any{}
+			if len(trip.DestinationCities) > 0 {
+				arguments["destination"] = trip.DestinationCities[0]
+			}
+			return stubToolCall(payload, "search_accommodations", arguments)
+		}
+		accommodations = result.Accommodations
+	} else if err := json.Unmarshal([]byte(payload.AccommodationOptions), &accommodations); err != nil {
+		return "", fmt.Errorf("failed to parse accommodation options: %v", err)
+	}
+
+	response := "I couldn't find any accommodation for your trip."
+	options := []map[string]string{}
+	for i, accommodation := range accommodations {
+		if i == 3 {
+			break
+		}
+		options = append(options, map[string]string{
+			"accommodation_id": accommodation.AccommodationID,
+			"reason":           fmt.Sprintf("%d-star %s in %s.", accommodation.StarRating, strings.ToLower(accommodation.Type), accommodation.City),
+		})
+	}
+	if len(options) > 0 {
+		response = fmt.Sprintf("I recommend staying at %s.", accommodations[0].Name)
+	}
+
+	return stubFencedResponse(map[string]any{
+		"accommodation_options": options,
+		"response":              response,
+	})
+}
+
+// stubFencedResponse wraps a structured answer the way the deployed
+// functions do: a JSON code fence inside the "response" string of the body.
+func stubFencedResponse(answer any) (string, error) {
+	answerJSON, err := json.Marshal(answer)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(TextResponse{
+		SchemaVersion: SchemaVersion,
+		Response:      "```json\n" + string(answerJSON) + "\n```",
+	})
+	return string(body), err
+}
+
+func stubOffersTool(payload LambdaPayload, name string) bool {
+	for _, tool := range payload.Tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stubToolResult decodes the latest successful result of the named tool
+// into target and reports whether there was one.
+func stubToolResult(payload LambdaPayload, name string, target any) bool {
+	for i := len(payload.ToolSteps) - 1; i >= 0; i-- {
+		step := payload.ToolSteps[i]
+		if step.Call.Name == name && step.Error == "" {
+			return json.Unmarshal(step.Result, target) == nil
+		}
+	}
+	return false
+}
+
+func stubToolCall(payload LambdaPayload, name string, arguments any) (string, error) {
+	argumentsJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(ToolCallsResponse{
+		SchemaVersion: SchemaVersion,
+		ToolCalls:     []ToolCall{{ID: fmt.Sprintf("call_%d", len(payload.ToolSteps)+1), Name: name, Arguments: argumentsJSON}},
+	})
+	return string(body), err
+}
@@ -0,0 +1,177 @@
+package lda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Functions offered tools may answer with tool calls instead of a final
+// answer:
+//
+//	{"tool_calls": [{"id": "1", "name": "search_flights", "arguments": {...}}]}
+//
+// RunTools executes the calls and invokes the function again with every
+// step so far in the payload's tool_steps, until it answers with anything
+// else, which is returned as its final response.
+
+// ToolSpec declares a backend function the agent may call.
+type ToolSpec struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters"`
+}
+
+// ToolCall is a call the agent asked for.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolStep is an executed tool call with its result, or the error that
+// prevented it from running.
+type ToolStep struct {
+	Function   string          `json:"function"`
+	Round      int             `json:"round"`
+	Call       ToolCall        `json:"call"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// Tool is a ToolSpec together with the code that runs it. Run receives
+// arguments already validated against the spec's Parameters and returns a
+// result that is sent back to the agent as JSON.
+type Tool struct {
+	ToolSpec
+	Run func(ctx context.Context, arguments json.RawMessage) (any, error)
+}
+
+// Toolbox is the set of tools offered on one call, and the number of rounds
+// of tool calls the agent gets before it has to answer.
+type Toolbox struct {
+	MaxRounds int
+	tools     []Tool
+}
+
+func NewToolbox(maxRounds int, tools ...Tool) *Toolbox {
+	return &Toolbox{MaxRounds: maxRounds, tools: tools}
+}
+
+// Specs returns the declarations of the tools, in the order they were added.
+func (b *Toolbox) Specs() []ToolSpec {
+	specs := make([]ToolSpec, len(b.tools))
+	for i, tool := range b.tools {
+		specs[i] = tool.ToolSpec
+	}
+	return specs
+}
+
+func (b *Toolbox) find(name string) (Tool, bool) {
+	for _, tool := range b.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// run executes one call. Unknown tools, invalid arguments and failing tools
+// are reported to the agent in the step rather than failing the turn, so it
+// can correct itself.
+func (b *Toolbox) run(ctx context.Context, function string, round int, call ToolCall) ToolStep {
+	started := time.Now()
+	result, err := b.execute(ctx, call)
+
+	step := ToolStep{
+		Function:   function,
+		Round:      round,
+		Call:       call,
+		Result:     result,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+func (b *Toolbox) execute(ctx context.Context, call ToolCall) (json.RawMessage, error) {
+	tool, ok := b.find(call.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	arguments := call.Arguments
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+	if errs := tool.Parameters.Validate(arguments); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid arguments: %s", strings.Join(errs, "; "))
+	}
+
+	result, err := tool.Run(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return data, nil
+}
+
+// ToolLimitError is returned when a function still asks for tools after
+// its toolbox's MaxRounds.
+type ToolLimitError struct {
+	Function string
+	Rounds   int
+}
+
+func (e *ToolLimitError) Error() string {
+	return fmt.Sprintf("%s did not answer within %d rounds of tool calls", e.Function, e.Rounds)
+}
+
+// RunTools calls function with the toolbox's tools on offer and runs the
+// tool calls it answers with until it gives a final response. Every step is
+// added to the Trace of ctx, if it has one.
+func RunTools(ctx context.Context,
+	function string,
+	call func(ctx context.Context, payload LambdaPayload) (*LambdaResponse, error),
+	payload LambdaPayload,
+	toolbox *Toolbox,
+) (*LambdaResponse, error) {
+	payload.Tools = toolbox.Specs()
+	payload.ToolSteps = append([]ToolStep(nil), payload.ToolSteps...)
+	trace := traceFrom(ctx)
+
+	for round := 1; ; round++ {
+		response, err := call(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		var body struct {
+			ToolCalls []ToolCall `json:"tool_calls"`
+		}
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil || len(body.ToolCalls) == 0 {
+			return response, nil
+		}
+		if round > toolbox.MaxRounds {
+			return nil, &ToolLimitError{Function: function, Rounds: toolbox.MaxRounds}
+		}
+
+		for _, toolCall := range body.ToolCalls {
+			step := toolbox.run(ctx, function, round, toolCall)
+			if step.Error != "" {
+				log.Printf("Tool %s called by %s failed: %s", toolCall.Name, function, step.Error)
+			}
+			payload.ToolSteps = append(payload.ToolSteps, step)
+			trace.add(step)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package lda
+
+import (
+	"context"
+	"sync"
+)
+
+// AgentCall is a request sent to a function, as the payload it was built
+// from.
+type AgentCall struct {
+	Function string        `json:"function"`
+	Kind     Kind          `json:"kind"`
+	Payload  LambdaPayload `json:"payload"`
+}
+
+// Trace collects the requests sent, what they cost and the tool steps run by
+// every function called with a context carrying it, e.g. all agent calls of
+// one chat turn. It is safe for concurrent use.
+type Trace struct {
+	mu    sync.Mutex
+	calls []AgentCall
+	usage []Usage
+	steps []ToolStep
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context whose requests and tool steps are added to
+// trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+func traceFrom(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+func (t *Trace) add(step ToolStep) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+func (t *Trace) addCall(call AgentCall) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, call)
+}
+
+func (t *Trace) addUsage(usage Usage) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage = append(t.usage, usage)
+}
+
+// Steps returns the steps collected so far.
+func (t *Trace) Steps() []ToolStep {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]ToolStep(nil), t.steps...)
+}
+
+// Calls returns the requests collected so far, in the order they were sent.
+func (t *Trace) Calls() []AgentCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]AgentCall(nil), t.calls...)
+}
+
+// Usage returns the usage of the calls that have finished so far.
+func (t *Trace) Usage() []Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Usage(nil), t.usage...)
+}
@@ -0,0 +1,80 @@
+package lda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Usage is what one agent call cost, over all of its attempts.
+type Usage struct {
+	Function string
+	Kind     Kind
+	Attempts int
+	// Duration is how long the backend waited for the answer, including
+	// retries. BilledDuration is what Lambda billed for every attempt, read
+	// from the invoke log tail, and is zero for the other backends.
+	Duration       time.Duration
+	BilledDuration time.Duration
+	RequestBytes   int
+	ResponseBytes  int
+	// InputTokens and OutputTokens are the tokens the function reported its
+	// model used, see TokenUsage.
+	InputTokens  int
+	OutputTokens int
+	Failed       bool
+}
+
+// TokenUsage is how a function reports the tokens its model used, next to
+// the rest of its answer:
+//
+//	{"schema_version": 2, "response": "...", "usage": {"input_tokens": 812, "output_tokens": 96}}
+type TokenUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type usageKey struct{}
+
+func withUsage(ctx context.Context, usage *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, usage)
+}
+
+func usageFrom(ctx context.Context) *Usage {
+	usage, _ := ctx.Value(usageKey{}).(*Usage)
+	return usage
+}
+
+// addTokens adds the tokens reported in an answer's body, if any.
+func (u *Usage) addTokens(body string) {
+	var answer struct {
+		Usage TokenUsage `json:"usage"`
+	}
+	if json.Unmarshal([]byte(body), &answer) == nil {
+		u.InputTokens += answer.Usage.InputTokens
+		u.OutputTokens += answer.Usage.OutputTokens
+	}
+}
+
+var billedDurationPattern = regexp.MustCompile(`Billed Duration: ([0-9.]+) ms`)
+
+// billedDuration reads the billed duration from the REPORT line of a
+// base64 encoded Lambda log tail.
+func billedDuration(logResult string) time.Duration {
+	logs, err := base64.StdEncoding.DecodeString(logResult)
+	if err != nil {
+		return 0
+	}
+	match := billedDurationPattern.FindSubmatch(logs)
+	if match == nil {
+		return 0
+	}
+	ms, err := strconv.ParseFloat(string(match[1]), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -5,10 +5,10 @@ import (
 	"log"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/yihao03/Aistronaut/m/v2/db"
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
 	"github.com/yihao03/Aistronaut/m/v2/lda"
 	"github.com/yihao03/Aistronaut/m/v2/router"
 )
@@ -23,13 +23,14 @@ func main() {
 	}
 
 	ctx := context.Background()
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		log.Fatal("Unable to load AWS SDK config:", err)
+	if err := lda.Init(ctx); err != nil {
+		log.Fatal("Failed to initialize agent:", err)
+		return
+	}
+	if err := guardrail.Init(); err != nil {
+		log.Fatal("Failed to load guardrail rules:", err)
 		return
 	}
-
-	lda.Init(ctx, cfg)
 
 	r := gin.Default()
 
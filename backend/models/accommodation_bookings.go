@@ -5,7 +5,14 @@ type AccommodationBookings struct {
 	UserID          string `gorm:"index"`
 	AccommodationID string
 	TripID          string
+	CheckInDate     string
+	CheckOutDate    string
+	NumberOfRooms   int
 	CreatedAt       RFC3339Time    `gorm:"index;primaryKey"`
 	UpdatedAt       RFC3339Time    `gorm:"autoUpdateTime"`
 	Accomodation    Accommodations `gorm:"foreignKey:AccommodationID;references:AccommodationID"`
+
+	// Set when a change to the trip's requirements no longer fits the booking.
+	Invalidated       bool
+	InvalidatedReason string
 }
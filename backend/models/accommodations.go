@@ -18,6 +18,8 @@ type Accommodations struct {
 	StarRating         int
 	Amenities          string `gorm:"type:text"` // Changed from []string to string
 	RoomTypes          string `gorm:"type:text"` // Changed from []string to string
+	TotalRooms         int    // 0 if unknown
+	MaxGuestsPerRoom   int    // 0 if unknown
 	CheckInTime        string
 	CheckOutTime       string
 	CancellationPolicy *string
@@ -39,6 +41,14 @@ type Accommodations struct {
 	UpdatedAt          RFC3339Time `gorm:"autoUpdateTime"`
 }
 
+// AccommodationRecommendation is an agent's pick from the accommodations it
+// was offered. Accommodation is filled in by the backend.
+type AccommodationRecommendation struct {
+	AccommodationID string          `json:"accommodation_id"`
+	Reason          string          `json:"reason"`
+	Accommodation   *Accommodations `json:"accommodation,omitempty"`
+}
+
 // Helper methods to work with Amenities as array
 func (a *Accommodations) GetAmenitiesArray() []string {
 	if a.Amenities == "" {
@@ -0,0 +1,14 @@
+package models
+
+// AgentTrace keeps the requests sent to the agents and the tool calls they
+// made while answering one chat turn, keyed by the agent message that
+// answered it. Calls is the JSON encoded list of lda.AgentCall and Steps
+// that of lda.ToolStep, with long values clipped to keep the item small.
+type AgentTrace struct {
+	TripID    string      `json:"trip_id" gorm:"primaryKey"`
+	ChatID    string      `json:"chat_id" gorm:"primaryKey"`
+	UserID    string      `json:"user_id"`
+	Calls     string      `json:"calls" gorm:"type:text"`
+	Steps     string      `json:"steps" gorm:"type:text"`
+	CreatedAt RFC3339Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// AgentUsage records what one agent call cost, see lda.Usage. Day is the
+// UTC date of the call, so usage can be totalled per user and day.
+type AgentUsage struct {
+	UserID           string      `json:"user_id" gorm:"primaryKey"`
+	CallID           string      `json:"call_id" gorm:"primaryKey"`
+	TripID           string      `json:"trip_id"`
+	Day              string      `json:"day"`
+	Function         string      `json:"function"`
+	Kind             string      `json:"kind"`
+	Attempts         int         `json:"attempts"`
+	DurationMs       int64       `json:"duration_ms"`
+	BilledDurationMs int64       `json:"billed_duration_ms"`
+	RequestBytes     int         `json:"request_bytes"`
+	ResponseBytes    int         `json:"response_bytes"`
+	InputTokens      int         `json:"input_tokens"`
+	OutputTokens     int         `json:"output_tokens"`
+	Failed           bool        `json:"failed"`
+	CreatedAt        RFC3339Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (AgentUsage) TableName() string {
+	return "agent_usage"
+}
+
+// DailyUsage counts the agent calls a user made on a UTC day, so the daily
+// quota can be checked without reading every AgentUsage of the day.
+type DailyUsage struct {
+	UserID    string      `json:"user_id" gorm:"primaryKey"`
+	Day       string      `json:"day" gorm:"primaryKey"`
+	Calls     int         `json:"calls"`
+	UpdatedAt RFC3339Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (DailyUsage) TableName() string {
+	return "agent_daily_usage"
+}
@@ -0,0 +1,12 @@
+package models
+
+// Airports places an airport code in a city and country, so flights, which
+// only carry codes, can be matched against trips, which carry names. An
+// airport serving several cities has a row for each.
+type Airports struct {
+	AirportCode string `gorm:"primaryKey"`
+	City        string `gorm:"primaryKey"`
+	Country     string
+	CreatedAt   RFC3339Time `gorm:"autoCreateTime"`
+	UpdatedAt   RFC3339Time `gorm:"autoUpdateTime"`
+}
@@ -4,16 +4,31 @@ import (
 	"encoding/json"
 )
 
+// Content types of a user message. Every type but ContentText is a
+// structured answer, e.g. a click on an option box, which the backend
+// applies itself without asking the agent.
+const (
+	ContentText            int32 = 0
+	ContentOptionSelection int32 = 1
+	ContentDateRange       int32 = 2
+	ContentTravelerCount   int32 = 3
+	ContentConfirmation    int32 = 4
+)
+
 type ChatHistory struct {
-	ChatHistoryID       string `gorm:"column:chat_history_id"`
-	ChatID              string `gorm:"column:chat_id"`
+	ChatHistoryID       string `gorm:"column:chat_history_id;primaryKey"`
+	ChatID              string `gorm:"column:chat_id;primaryKey"`
+	ParentID            string `gorm:"column:parent_id"` // chat ID of the previous message on the same branch
 	UserID              string
 	UserOrAgent         string
 	Message             string
+	ContentType         int32
 	ReqObject           string
 	FlightObject        string
 	AccommodationObject string
-	Timestamp           RFC3339Time `gorm:"autoCreateTime;primaryKey"`
+	Guardrail           string      // guardrail action on a user message; "block" on the canned reply to one
+	GuardrailRules      StringArray `gorm:"type:text"` // guardrail rules a user message matched
+	Timestamp           RFC3339Time `gorm:"autoCreateTime"`
 }
 
 func (ChatHistory) TableName() string {
@@ -0,0 +1,12 @@
+package models
+
+// ConversationSummary is the rolling summary of the turns of a conversation
+// that no longer fit in the history sent to the agent: a digest of the
+// requirements and decisions they settled and of what the user said, one
+// "key: value" line per fact.
+type ConversationSummary struct {
+	ConversationID    string `gorm:"primaryKey"`
+	Summary           string
+	SummarizedThrough string      // chat ID of the newest summarized message
+	UpdatedAt         RFC3339Time `gorm:"autoUpdateTime"`
+}
@@ -8,4 +8,8 @@ type FlightBookings struct {
 	Flight    Flights     `gorm:"foreignKey:FlightID;references:FlightID"`
 	CreatedAt RFC3339Time `gorm:"autoCreateTime;index;primaryKey"`
 	UpdatedAt RFC3339Time `gorm:"autoUpdateTime"`
+
+	// Set when a change to the trip's requirements no longer fits the booking.
+	Invalidated       bool
+	InvalidatedReason string
 }
@@ -21,3 +21,10 @@ type Flights struct {
 	CreatedAt         RFC3339Time `gorm:"autoCreateTime"`
 	UpdatedAt         RFC3339Time `gorm:"autoUpdateTime"`
 }
+
+// ModeError records why planning a single mode failed while the others
+// may still have succeeded.
+type ModeError struct {
+	Mode  string `json:"mode"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// Idempotency record statuses.
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyRecord remembers the response to the first request a user sent
+// with a given Idempotency-Key so retries can be answered without running
+// the request again.
+type IdempotencyRecord struct {
+	UserID         string `gorm:"primaryKey"`
+	IdempotencyKey string `gorm:"primaryKey"`
+	Method         string
+	Path           string
+	RequestHash    string
+	Status         string
+	ResponseStatus int
+	ContentType    string
+	ResponseBody   string
+	CreatedAt      RFC3339Time `gorm:"autoCreateTime"`
+	UpdatedAt      RFC3339Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// Itinerary is the day-by-day plan the trip planner builds for one mode of
+// a trip. A trip has at most one itinerary per mode.
+type Itinerary struct {
+	TripID             string        `json:"trip_id" gorm:"primaryKey"`
+	Mode               string        `json:"mode" gorm:"primaryKey"`
+	UserID             string        `json:"user_id"`
+	Summary            string        `json:"summary"`
+	Days               ItineraryDays `json:"days" gorm:"type:text"`
+	TotalEstimatedCost float64       `json:"total_estimated_cost"`
+	Currency           string        `json:"currency"`
+	CreatedAt          RFC3339Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          RFC3339Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type ItineraryDay struct {
+	Day    int         `json:"day"`
+	Date   string      `json:"date"`
+	Title  string      `json:"title"`
+	Blocks []TimeBlock `json:"blocks"`
+}
+
+// TimeBlock is a single activity within a day.
+type TimeBlock struct {
+	StartTime     string  `json:"start_time"`
+	EndTime       string  `json:"end_time"`
+	Activity      string  `json:"activity"`
+	Description   string  `json:"description"`
+	Location      string  `json:"location"`
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// ItineraryDays is stored as a JSON encoded string.
+type ItineraryDays []ItineraryDay
+
+func (d *ItineraryDays) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("cannot scan non-string into ItineraryDays")
+	}
+	if len(data) == 0 {
+		*d = nil
+		return nil
+	}
+	return json.Unmarshal(data, d)
+}
+
+func (d ItineraryDays) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]ItineraryDay(d))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
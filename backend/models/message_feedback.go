@@ -0,0 +1,34 @@
+package models
+
+// Feedback ratings.
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// FeedbackReasons are the categories a rating can be given for.
+var FeedbackReasons = []string{
+	"incorrect",
+	"unhelpful",
+	"incomplete",
+	"ignored_request",
+	"wrong_language",
+	"other",
+}
+
+// MessageFeedback is a user's rating of an agent message, keyed by the
+// message. Rating again replaces the earlier rating.
+type MessageFeedback struct {
+	TripID    string      `json:"trip_id" gorm:"primaryKey"`
+	ChatID    string      `json:"chat_id" gorm:"primaryKey"`
+	UserID    string      `json:"user_id"`
+	Rating    string      `json:"rating"`
+	Reason    string      `json:"reason"`
+	Comment   string      `json:"comment" gorm:"type:text"`
+	CreatedAt RFC3339Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt RFC3339Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (MessageFeedback) TableName() string {
+	return "message_feedback"
+}
@@ -25,4 +25,5 @@ type TripPlans struct {
 	SelectedFlight  SelectedFlight `json:"selected_flight"`
 	TripPreferences *string        `json:"trip_preferences"`
 	Mode            string         `json:"mode"`
+	Itinerary       *Itinerary     `json:"itinerary,omitempty"`
 }
@@ -0,0 +1,79 @@
+package models
+
+import "fmt"
+
+// Conversation stages, in the order a trip moves through them.
+const (
+	StageCollectingRequirements = "collecting_requirements"
+	StageChoosingFlight         = "choosing_flight"
+	StageChoosingAccommodation  = "choosing_accommodation"
+	StageReviewing              = "reviewing"
+	StageConfirmed              = "confirmed"
+)
+
+var stageOrder = []string{
+	StageCollectingRequirements,
+	StageChoosingFlight,
+	StageChoosingAccommodation,
+	StageReviewing,
+	StageConfirmed,
+}
+
+func stageIndex(stage string) int {
+	for i, s := range stageOrder {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsValidStage reports whether stage is one of the known stages.
+func IsValidStage(stage string) bool {
+	return stageIndex(stage) >= 0
+}
+
+// PreviousStage returns the stage before the given one. A confirmed trip
+// cannot be moved back.
+func PreviousStage(stage string) (string, bool) {
+	i := stageIndex(stage)
+	if i <= 0 || stage == StageConfirmed {
+		return "", false
+	}
+	return stageOrder[i-1], true
+}
+
+// ValidateStageTransition allows a trip to advance exactly one stage, or to
+// move back one stage as long as it has not been confirmed.
+func ValidateStageTransition(from, to string) error {
+	i, j := stageIndex(from), stageIndex(to)
+	if i < 0 {
+		return fmt.Errorf("unknown stage %q", from)
+	}
+	if j < 0 {
+		return fmt.Errorf("unknown stage %q", to)
+	}
+	if j == i+1 {
+		return nil
+	}
+	if prev, ok := PreviousStage(from); ok && prev == to {
+		return nil
+	}
+	return fmt.Errorf("cannot move from %s to %s", from, to)
+}
+
+// ValidateStageRewind allows an unconfirmed trip to jump back to any earlier
+// stage, as happens when a requirement change invalidates its bookings.
+func ValidateStageRewind(from, to string) error {
+	i, j := stageIndex(from), stageIndex(to)
+	if i < 0 {
+		return fmt.Errorf("unknown stage %q", from)
+	}
+	if j < 0 {
+		return fmt.Errorf("unknown stage %q", to)
+	}
+	if from == StageConfirmed || j >= i {
+		return fmt.Errorf("cannot rewind from %s to %s", from, to)
+	}
+	return nil
+}
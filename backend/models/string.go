@@ -18,6 +18,10 @@ func (sa *StringArray) Scan(value interface{}) error {
 	if !ok {
 		return errors.New("cannot scan non-string into StringArray")
 	}
+	if str == "" {
+		*sa = nil
+		return nil
+	}
 	*sa = strings.Split(str, ",")
 	return nil
 }
@@ -45,6 +49,10 @@ func (sa *StringArray) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
+	if s == "" {
+		*sa = nil
+		return nil
+	}
 	*sa = strings.Split(s, ",")
 	return nil
 }
@@ -7,6 +7,9 @@ import (
 	"time"
 )
 
+// RFC3339Time is stored as an RFC 3339 string. Because gorm sees a string
+// column, it fills autoUpdateTime fields with Unix seconds on Update and
+// Updates, so those calls must set updated_at themselves.
 type RFC3339Time time.Time
 
 func (t *RFC3339Time) Scan(value any) error {
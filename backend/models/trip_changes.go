@@ -0,0 +1,25 @@
+package models
+
+// Trip change statuses.
+const (
+	ChangeApplied  = "applied"
+	ChangePending  = "pending"
+	ChangeRejected = "rejected"
+)
+
+// TripChange records one field of a trip's requirements being set or
+// changed. Values are JSON encoded. A change that would invalidate an
+// existing booking stays pending until the user confirms it.
+type TripChange struct {
+	ChangeID                 string      `json:"change_id" gorm:"primaryKey"`
+	TripID                   string      `json:"trip_id" gorm:"index"`
+	UserID                   string      `json:"user_id"`
+	Field                    string      `json:"field"`
+	OldValue                 string      `json:"old_value"`
+	NewValue                 string      `json:"new_value"`
+	Status                   string      `json:"status"`
+	InvalidatesFlight        bool        `json:"invalidates_flight"`
+	InvalidatesAccommodation bool        `json:"invalidates_accommodation"`
+	CreatedAt                RFC3339Time `json:"created_at" gorm:"autoCreateTime;primaryKey"`
+	UpdatedAt                RFC3339Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
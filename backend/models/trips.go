@@ -1,13 +1,22 @@
 package models
 
+// OptionalTripFields are the JSON names of the trip requirements that may
+// stay empty once the others are collected.
+var OptionalTripFields = map[string]bool{
+	"origin_city":    true,
+	"children_count": true,
+	"infants_count":  true,
+}
+
 type Trip struct {
 	TripID              string      `json:"trip_id" gorm:"primaryKey"`
 	UserID              string      `json:"user_id" gorm:"primaryKey"`
 	TripName            string      `json:"trip_name"`
+	OriginCity          string      `json:"origin_city"`
 	DestinationCountry  StringArray `json:"destination_country" gorm:"type:text"`
 	DestinationCities   StringArray `json:"destination_cities" gorm:"type:text"`
 	Landmarks           StringArray `json:"landmarks" gorm:"type:text"`
-	StartDate           string      `json:"start_date" gorm:"primaryKey"`
+	StartDate           string      `json:"start_date"`
 	EndDate             string      `json:"end_date"`
 	TotalBudget         int         `json:"total_budget"`
 	NumberOfTravelers   int         `json:"number_of_travelers"`
@@ -18,4 +27,10 @@ type Trip struct {
 	Purpose             string      `json:"purpose"`
 	Notes               string      `json:"notes"`
 	DietaryRestrictions string      `json:"dietary_restrictions"`
+	Stage               string      `json:"stage"`
+	ActiveLeafID        string      `json:"active_leaf_id"`
+	// Kept up to date as messages are added, for listing conversations.
+	CreatedAt          RFC3339Time `json:"-"`
+	LastActivityAt     RFC3339Time `json:"-"`
+	LastMessagePreview string      `json:"-"`
 }
@@ -11,6 +11,7 @@ type Users struct {
 	DateOfBirth RFC3339Time
 	PassportNum string      `gorm:"size:50;unique"`
 	Nationality string      `gorm:"size:100"`
+	Locale      string      `gorm:"size:10"`
 	CreatedAt   RFC3339Time `gorm:"autoCreateTime;primaryKey"`
 	UpdatedAt   RFC3339Time `gorm:"autoUpdateTime"`
 	DeletedAt   RFC3339Time `gorm:"index"`
@@ -2,16 +2,99 @@ package chatparams
 
 import (
 	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yihao03/Aistronaut/m/v2/models"
 )
 
+// Options that can be selected with a ContentOptionSelection answer.
+const (
+	OptionMode          = "mode"
+	OptionFlight        = "flight"
+	OptionAccommodation = "accommodation"
+)
+
 type CreateParams struct {
 	ChatHistoryID string `json:"conversation_id"`
 	UserID        string `json:"user_id"`
 	Content       string `json:"content" binding:"required"`
 	ContentType   int32  `json:"content_type"`
+	// Answer holds the structured answer when ContentType is not
+	// models.ContentText. Content is then what the user is shown as having
+	// said, e.g. the label of the option they clicked.
+	Answer *Answer `json:"answer,omitempty"`
+}
+
+// Answer is a structured answer. Only the fields of the message's content
+// type are read.
+type Answer struct {
+	// ContentOptionSelection: Option is one of OptionMode, OptionFlight or
+	// OptionAccommodation and OptionID the mode, flight ID or accommodation ID.
+	Option   string `json:"option,omitempty"`
+	OptionID string `json:"option_id,omitempty"`
+
+	// ContentDateRange, as YYYY-MM-DD.
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+
+	// ContentTravelerCount.
+	Adults   int `json:"adults,omitempty"`
+	Children int `json:"children,omitempty"`
+	Infants  int `json:"infants,omitempty"`
+
+	// ContentConfirmation.
+	Confirmed *bool `json:"confirmed,omitempty"`
+}
+
+// Validate checks that a structured message carries the answer of its
+// content type.
+func (p CreateParams) Validate() error {
+	if p.ContentType == models.ContentText {
+		return nil
+	}
+	if p.ContentType < models.ContentText || p.ContentType > models.ContentConfirmation {
+		return fmt.Errorf("unknown content_type: %d", p.ContentType)
+	}
+	if p.Answer == nil {
+		return fmt.Errorf("answer is required for content_type %d", p.ContentType)
+	}
+
+	a := p.Answer
+	switch p.ContentType {
+	case models.ContentOptionSelection:
+		if a.Option != OptionMode && a.Option != OptionFlight && a.Option != OptionAccommodation {
+			return fmt.Errorf("option must be one of %s, %s, %s, got: %s", OptionMode, OptionFlight, OptionAccommodation, a.Option)
+		}
+		if a.OptionID == "" {
+			return fmt.Errorf("option_id is required")
+		}
+	case models.ContentDateRange:
+		start, err := time.Parse("2006-01-02", a.StartDate)
+		if err != nil {
+			return fmt.Errorf("invalid start_date format, use YYYY-MM-DD: %s", a.StartDate)
+		}
+		end, err := time.Parse("2006-01-02", a.EndDate)
+		if err != nil {
+			return fmt.Errorf("invalid end_date format, use YYYY-MM-DD: %s", a.EndDate)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("end_date must not be before start_date")
+		}
+	case models.ContentTravelerCount:
+		if a.Adults < 1 {
+			return fmt.Errorf("adults must be at least 1, got: %d", a.Adults)
+		}
+		if a.Children < 0 || a.Infants < 0 {
+			return fmt.Errorf("children and infants must not be negative")
+		}
+	case models.ContentConfirmation:
+		if a.Confirmed == nil {
+			return fmt.Errorf("confirmed is required")
+		}
+	}
+	return nil
 }
 
 func (p CreateParams) ToModel(userID string, object any) *models.ChatHistory {
@@ -25,7 +108,9 @@ func (p CreateParams) ToModel(userID string, object any) *models.ChatHistory {
 		ChatHistoryID: string(p.ChatHistoryID),
 		UserID:        userID,
 		ChatID:        uuid.New().String(),
+		UserOrAgent:   "user",
 		Message:       p.Content,
+		ContentType:   p.ContentType,
 		ReqObject:     string(objectString),
 		Timestamp:     models.Now(),
 	}
@@ -0,0 +1,5 @@
+package chatparams
+
+type EditParams struct {
+	Content string `json:"content" binding:"required"`
+}
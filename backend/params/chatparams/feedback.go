@@ -0,0 +1,33 @@
+package chatparams
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// MaxFeedbackCommentLength bounds a feedback comment, in characters.
+const MaxFeedbackCommentLength = 2000
+
+type FeedbackParams struct {
+	Rating  string `json:"rating" binding:"required"`
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+// Validate checks the rating, reason category and comment length.
+func (p FeedbackParams) Validate() error {
+	if p.Rating != models.RatingUp && p.Rating != models.RatingDown {
+		return fmt.Errorf("rating must be %s or %s, got: %s", models.RatingUp, models.RatingDown, p.Rating)
+	}
+	if p.Reason != "" && !slices.Contains(models.FeedbackReasons, p.Reason) {
+		return fmt.Errorf("reason must be one of %s, got: %s", strings.Join(models.FeedbackReasons, ", "), p.Reason)
+	}
+	if utf8.RuneCountInString(p.Comment) > MaxFeedbackCommentLength {
+		return fmt.Errorf("comment must be at most %d characters", MaxFeedbackCommentLength)
+	}
+	return nil
+}
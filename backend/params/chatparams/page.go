@@ -0,0 +1,37 @@
+package chatparams
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// PageParams for the cursor paginated chat endpoints
+type PageParams struct {
+	Limit  *string `json:"limit,omitempty" form:"limit"`
+	Cursor *string `json:"cursor,omitempty" form:"cursor"`
+}
+
+// GetLimitInt converts Limit string to int, falling back to the default
+func (p PageParams) GetLimitInt() (int, error) {
+	if p.Limit == nil {
+		return DefaultPageLimit, nil
+	}
+	return strconv.Atoi(*p.Limit)
+}
+
+// Validate checks if the pagination parameters are valid
+func (p PageParams) Validate() error {
+	limit, err := p.GetLimitInt()
+	if err != nil {
+		return fmt.Errorf("invalid limit: %s", *p.Limit)
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return fmt.Errorf("limit must be between 1 and %d, got: %d", MaxPageLimit, limit)
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+package tripparams
+
+type ItineraryParams struct {
+	Mode *string `form:"mode"`
+}
@@ -5,4 +5,6 @@ type CreateParams struct {
 	Email       string `json:"email" binding:"required,email"`
 	Password    string `json:"password" binding:"required"`
 	Nationality string `json:"nationality" binding:"required"`
+	// Locale defaults to the one the request is made in.
+	Locale string `json:"locale"`
 }
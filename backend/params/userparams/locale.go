@@ -0,0 +1,5 @@
+package userparams
+
+type LocaleParams struct {
+	Locale string `json:"locale" binding:"required"`
+}
@@ -0,0 +1,56 @@
+package userparams
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultUsageDays is how many days of usage are returned when no
+	// range is given, up to and including today.
+	DefaultUsageDays = 30
+	MaxUsageDays     = 366
+)
+
+// UsageParams selects the UTC days, as YYYY-MM-DD, to total usage over.
+type UsageParams struct {
+	From *string `json:"from,omitempty" form:"from"`
+	To   *string `json:"to,omitempty" form:"to"`
+}
+
+// Range returns the first and last day of the range, defaulting to the last
+// DefaultUsageDays days.
+func (p UsageParams) Range() (string, string) {
+	end := time.Now().UTC()
+	to := end.Format("2006-01-02")
+	if p.To != nil {
+		to = *p.To
+		end, _ = time.Parse("2006-01-02", to)
+	}
+	from := end.AddDate(0, 0, 1-DefaultUsageDays).Format("2006-01-02")
+	if p.From != nil {
+		from = *p.From
+	}
+	return from, to
+}
+
+// Validate checks that the range is made of valid days in order and spans
+// at most MaxUsageDays.
+func (p UsageParams) Validate() error {
+	from, to := p.Range()
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return fmt.Errorf("from must be YYYY-MM-DD, got: %s", from)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return fmt.Errorf("to must be YYYY-MM-DD, got: %s", to)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("to must not be before from")
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; days > MaxUsageDays {
+		return fmt.Errorf("range must span at most %d days, got: %d", MaxUsageDays, days)
+	}
+	return nil
+}
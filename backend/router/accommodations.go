@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/accommodations"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/chat"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/idempotency"
 )
 
 func SetupAccommodationRoutes(r *gin.RouterGroup) {
@@ -12,7 +13,7 @@ func SetupAccommodationRoutes(r *gin.RouterGroup) {
 	r.GET("/search", accommodations.SearchAccommodations)
 	r.GET("/:id", accommodations.GetAccommodationByID)
 	r.GET("/city/:city", accommodations.GetAccommodationsByCity)
-	r.POST("/select", chat.SelectAccommodationHandler)
+	r.POST("/select", idempotency.Middleware(), chat.SelectAccommodationHandler)
 	// Protected routes would go here if needed (e.g., admin-only routes)
 	// protected := r.Group("/").Use(user.Authenticate())
 	// protected.POST("/", accommodations.CreateAccommodation) // Admin only
@@ -3,9 +3,23 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/chat"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/idempotency"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/usage"
 )
 
 func SetupChatRoutes(r *gin.RouterGroup) {
-	r.POST("/create", chat.CreateHandler)
-	r.POST("/", chat.ChatHandler)
+	r.GET("/", chat.ListHandler)
+	r.POST("/create", idempotency.Middleware(), chat.CreateHandler)
+	r.POST("/", idempotency.Middleware(), usage.Quota(), chat.ChatHandler)
+	r.POST("/stream", idempotency.Middleware(), usage.Quota(), chat.ChatStreamHandler)
+	r.GET("/:conversation_id/messages", chat.MessagesHandler)
+	r.GET("/:conversation_id/messages/:chat_id/trace", chat.TraceHandler)
+	r.POST("/:conversation_id/messages/:chat_id/feedback", chat.FeedbackHandler)
+	r.GET("/:conversation_id/usage", chat.UsageHandler)
+	r.POST("/:conversation_id/messages/:chat_id/edit", idempotency.Middleware(), usage.Quota(), chat.EditMessageHandler)
+	r.POST("/:conversation_id/regenerate", idempotency.Middleware(), usage.Quota(), chat.RegenerateHandler)
+	r.POST("/:conversation_id/stage/back", chat.StageBackHandler)
+	r.POST("/:conversation_id/confirm", chat.ConfirmHandler)
+	r.POST("/:conversation_id/changes/confirm", chat.ConfirmChangesHandler)
+	r.POST("/:conversation_id/changes/reject", chat.RejectChangesHandler)
 }
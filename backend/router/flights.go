@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/chat"
 	"github.com/yihao03/Aistronaut/m/v2/handlers/flights"
+	"github.com/yihao03/Aistronaut/m/v2/handlers/idempotency"
 )
 
 func SetupFlightRoutes(r *gin.RouterGroup) {
@@ -11,7 +12,7 @@ func SetupFlightRoutes(r *gin.RouterGroup) {
 	r.GET("/", flights.GetAllFlights)
 	r.GET("/search", flights.SearchFlights)
 	r.GET("/:id", flights.GetFlightByID)
-	r.POST("/select", chat.SelectFlightHandler)
+	r.POST("/select", idempotency.Middleware(), chat.SelectFlightHandler)
 
 	// Protected routes would go here if needed (e.g., admin-only routes)
 	// protected := r.Group("/").Use(user.Authenticate())
@@ -7,4 +7,5 @@ import (
 
 func SetupTripRoutes(r *gin.RouterGroup) {
 	r.GET("/:id", trip.HandleRead)
+	r.GET("/:id/itinerary", trip.HandleItinerary)
 }
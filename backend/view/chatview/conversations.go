@@ -0,0 +1,20 @@
+package chatview
+
+import "github.com/yihao03/Aistronaut/m/v2/models"
+
+type ConversationView struct {
+	ConversationID     string             `json:"conversation_id"`
+	TripName           string             `json:"trip_name"`
+	DestinationCountry models.StringArray `json:"destination_country"`
+	DestinationCities  models.StringArray `json:"destination_cities"`
+	StartDate          string             `json:"start_date"`
+	EndDate            string             `json:"end_date"`
+	Stage              string             `json:"stage"`
+	LastMessage        string             `json:"last_message"`
+	LastActivityAt     string             `json:"last_activity_at"`
+}
+
+type ConversationsResponse struct {
+	Conversations []ConversationView `json:"conversations"`
+	NextCursor    string             `json:"next_cursor,omitempty"`
+}
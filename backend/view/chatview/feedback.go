@@ -0,0 +1,60 @@
+package chatview
+
+import (
+	"encoding/json"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+type FeedbackResponse struct {
+	ConversationID string `json:"conversation_id"`
+	ChatID         string `json:"chat_id"`
+	Rating         string `json:"rating"`
+	Reason         string `json:"reason,omitempty"`
+	Comment        string `json:"comment,omitempty"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+func NewFeedbackResponse(feedback models.MessageFeedback) FeedbackResponse {
+	return FeedbackResponse{
+		ConversationID: feedback.TripID,
+		ChatID:         feedback.ChatID,
+		Rating:         feedback.Rating,
+		Reason:         feedback.Reason,
+		Comment:        feedback.Comment,
+		UpdatedAt:      feedback.UpdatedAt.ToString(),
+	}
+}
+
+// FeedbackExport is one rated agent message with the user message it
+// answered and the requests that produced it, as written by the feedback
+// export.
+type FeedbackExport struct {
+	ConversationID string          `json:"conversation_id"`
+	ChatID         string          `json:"chat_id"`
+	UserID         string          `json:"user_id"`
+	Rating         string          `json:"rating"`
+	Reason         string          `json:"reason,omitempty"`
+	Comment        string          `json:"comment,omitempty"`
+	RatedAt        string          `json:"rated_at"`
+	Prompt         string          `json:"prompt"`
+	Message        MessageView     `json:"message"`
+	Calls          json.RawMessage `json:"calls"`
+	Steps          json.RawMessage `json:"steps"`
+}
+
+func NewFeedbackExport(feedback models.MessageFeedback, prompt string, msg models.ChatHistory, trace models.AgentTrace) FeedbackExport {
+	return FeedbackExport{
+		ConversationID: feedback.TripID,
+		ChatID:         feedback.ChatID,
+		UserID:         feedback.UserID,
+		Rating:         feedback.Rating,
+		Reason:         feedback.Reason,
+		Comment:        feedback.Comment,
+		RatedAt:        feedback.UpdatedAt.ToString(),
+		Prompt:         prompt,
+		Message:        NewMessageView(msg),
+		Calls:          decodeList(trace.Calls),
+		Steps:          decodeList(trace.Steps),
+	}
+}
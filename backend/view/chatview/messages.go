@@ -0,0 +1,69 @@
+package chatview
+
+import (
+	"encoding/json"
+
+	"github.com/yihao03/Aistronaut/m/v2/guardrail"
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+type MessageView struct {
+	ChatID              string          `json:"chat_id"`
+	ParentID            string          `json:"parent_id,omitempty"`
+	ConversationID      string          `json:"conversation_id"`
+	Content             string          `json:"content"`
+	ContentType         int32           `json:"content_type"`
+	Object              json.RawMessage `json:"object,omitempty"`
+	FlightObject        json.RawMessage `json:"flight_object,omitempty"`
+	AccommodationObject json.RawMessage `json:"accommodation_object,omitempty"`
+	Guardrail           string          `json:"guardrail,omitempty"`
+	GuardrailRules      []string        `json:"guardrail_rules,omitempty"`
+	CreatedAt           string          `json:"created_at"`
+	IsUser              bool            `json:"is_user"`
+}
+
+type MessagesResponse struct {
+	ConversationID string        `json:"conversation_id"`
+	Messages       []MessageView `json:"messages"`
+	NextCursor     string        `json:"next_cursor,omitempty"`
+}
+
+func NewMessageView(msg models.ChatHistory) MessageView {
+	return MessageView{
+		ChatID:              msg.ChatID,
+		ParentID:            msg.ParentID,
+		ConversationID:      msg.ChatHistoryID,
+		Content:             msg.Message,
+		ContentType:         msg.ContentType,
+		Object:              decodeObject(msg.ReqObject),
+		FlightObject:        decodeObject(msg.FlightObject),
+		AccommodationObject: decodeObject(msg.AccommodationObject),
+		Guardrail:           guardrailAction(msg.Guardrail),
+		GuardrailRules:      msg.GuardrailRules,
+		CreatedAt:           msg.Timestamp.ToString(),
+		IsUser:              msg.UserOrAgent != "agent",
+	}
+}
+
+// decodeObject returns a stored JSON payload as raw JSON, dropping empty
+// payloads so they are omitted from the response.
+func decodeObject(object string) json.RawMessage {
+	switch object {
+	case "", "null", `""`, "{}", "[]":
+		return nil
+	}
+	if !json.Valid([]byte(object)) {
+		encoded, _ := json.Marshal(object)
+		return encoded
+	}
+	return json.RawMessage(object)
+}
+
+// guardrailAction drops the action of messages that passed the guardrails,
+// so only sanitized and blocked messages are marked.
+func guardrailAction(action string) string {
+	if action == guardrail.ActionPass {
+		return ""
+	}
+	return action
+}
@@ -1,11 +1,22 @@
 package chatview
 
+import "github.com/yihao03/Aistronaut/m/v2/models"
+
 type ChatResponse struct {
-	ConversationID      string `json:"conversation_id"`
-	Content             string `json:"content"`
-	Object              string `json:"object"`
-	FlightObject        string `json:"flight_object,omitempty"`
-	AccommodationObject string `json:"accommodation_object,omitempty"`
-	CreatedAt           string `json:"created_at"`
-	IsUser              bool   `json:"is_user"`
+	ConversationID      string              `json:"conversation_id"`
+	Content             string              `json:"content"`
+	Object              string              `json:"object"`
+	FlightObject        string              `json:"flight_object,omitempty"`
+	AccommodationObject string              `json:"accommodation_object,omitempty"`
+	ModeErrors          []models.ModeError  `json:"mode_errors,omitempty"`
+	PendingChanges      []models.TripChange `json:"pending_changes,omitempty"`
+	Stage               string              `json:"stage,omitempty"`
+	Blocked             bool                `json:"blocked,omitempty"`
+	CreatedAt           string              `json:"created_at"`
+	IsUser              bool                `json:"is_user"`
+}
+
+type StageResponse struct {
+	ConversationID string `json:"conversation_id"`
+	Stage          string `json:"stage"`
 }
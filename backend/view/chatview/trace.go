@@ -0,0 +1,36 @@
+package chatview
+
+import (
+	"encoding/json"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+type TraceResponse struct {
+	ConversationID string          `json:"conversation_id"`
+	ChatID         string          `json:"chat_id"`
+	Calls          json.RawMessage `json:"calls"`
+	Steps          json.RawMessage `json:"steps"`
+	CreatedAt      string          `json:"created_at,omitempty"`
+}
+
+func NewTraceResponse(conversationID, chatID string, trace models.AgentTrace) TraceResponse {
+	res := TraceResponse{
+		ConversationID: conversationID,
+		ChatID:         chatID,
+		Calls:          decodeList(trace.Calls),
+		Steps:          decodeList(trace.Steps),
+	}
+	if trace.ChatID != "" {
+		res.CreatedAt = trace.CreatedAt.ToString()
+	}
+	return res
+}
+
+// decodeList returns a stored JSON list, or an empty one if nothing is stored.
+func decodeList(list string) json.RawMessage {
+	if decoded := decodeObject(list); decoded != nil {
+		return decoded
+	}
+	return json.RawMessage("[]")
+}
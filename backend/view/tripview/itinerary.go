@@ -0,0 +1,8 @@
+package tripview
+
+import "github.com/yihao03/Aistronaut/m/v2/models"
+
+type ItineraryResponse struct {
+	TripID      string             `json:"trip_id"`
+	Itineraries []models.Itinerary `json:"itineraries"`
+}
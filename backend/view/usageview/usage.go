@@ -0,0 +1,93 @@
+package usageview
+
+import (
+	"sort"
+
+	"github.com/yihao03/Aistronaut/m/v2/models"
+)
+
+// Totals adds up the usage of a set of agent calls.
+type Totals struct {
+	Calls            int   `json:"calls"`
+	FailedCalls      int   `json:"failed_calls"`
+	DurationMs       int64 `json:"duration_ms"`
+	BilledDurationMs int64 `json:"billed_duration_ms"`
+	RequestBytes     int64 `json:"request_bytes"`
+	ResponseBytes    int64 `json:"response_bytes"`
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+}
+
+func (t *Totals) add(record models.AgentUsage) {
+	t.Calls++
+	if record.Failed {
+		t.FailedCalls++
+	}
+	t.DurationMs += record.DurationMs
+	t.BilledDurationMs += record.BilledDurationMs
+	t.RequestBytes += int64(record.RequestBytes)
+	t.ResponseBytes += int64(record.ResponseBytes)
+	t.InputTokens += int64(record.InputTokens)
+	t.OutputTokens += int64(record.OutputTokens)
+}
+
+// ConversationUsageResponse totals the agent calls of a conversation, in all
+// and per function.
+type ConversationUsageResponse struct {
+	ConversationID string `json:"conversation_id"`
+	Totals
+	Functions map[string]Totals `json:"functions"`
+}
+
+func NewConversationUsageResponse(conversationID string, records []models.AgentUsage) ConversationUsageResponse {
+	res := ConversationUsageResponse{ConversationID: conversationID, Functions: map[string]Totals{}}
+	for _, record := range records {
+		res.add(record)
+		totals := res.Functions[record.Function]
+		totals.add(record)
+		res.Functions[record.Function] = totals
+	}
+	return res
+}
+
+// DayUsage totals the agent calls of one UTC day.
+type DayUsage struct {
+	Day string `json:"day"`
+	Totals
+}
+
+// QuotaView is how much of today's quota of agent calls is left.
+type QuotaView struct {
+	Limit     int `json:"limit"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
+
+// UserUsageResponse totals a user's agent calls between two days, in all and
+// per day, oldest first. Quota is only set when a daily quota is enforced.
+type UserUsageResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Totals
+	Days  []DayUsage `json:"days"`
+	Quota *QuotaView `json:"quota,omitempty"`
+}
+
+func NewUserUsageResponse(from, to string, records []models.AgentUsage) UserUsageResponse {
+	res := UserUsageResponse{From: from, To: to, Days: []DayUsage{}}
+	days := map[string]*Totals{}
+	for _, record := range records {
+		res.add(record)
+		if days[record.Day] == nil {
+			days[record.Day] = &Totals{}
+		}
+		days[record.Day].add(record)
+	}
+	for day, totals := range days {
+		res.Days = append(res.Days, DayUsage{Day: day, Totals: *totals})
+	}
+	sort.Slice(res.Days, func(i, j int) bool {
+		return res.Days[i].Day < res.Days[j].Day
+	})
+	return res
+}
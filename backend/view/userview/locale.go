@@ -0,0 +1,5 @@
+package userview
+
+type LocaleResponse struct {
+	Locale string `json:"locale"`
+}
@@ -42,10 +42,10 @@ aws dynamodb create-table ^
     --attribute-definitions ^
         AttributeName=chat_history_id,AttributeType=S ^
         AttributeName=user_id,AttributeType=S ^
-        AttributeName=timestamp,AttributeType=S ^
+        AttributeName=chat_id,AttributeType=S ^
     --key-schema ^
         AttributeName=chat_history_id,KeyType=HASH ^
-        AttributeName=timestamp,KeyType=RANGE ^
+        AttributeName=chat_id,KeyType=RANGE ^
     --global-secondary-indexes ^
         IndexName=user_id-index,KeySchema=[{AttributeName=user_id,KeyType=HASH}],Projection={ProjectionType=ALL} ^
     --billing-mode PAY_PER_REQUEST
@@ -87,10 +87,8 @@ aws dynamodb create-table ^
     --attribute-definitions ^
         AttributeName=trip_id,AttributeType=S ^
         AttributeName=user_id,AttributeType=S ^
-        AttributeName=start_date,AttributeType=S ^
     --key-schema ^
         AttributeName=trip_id,KeyType=HASH ^
-        AttributeName=start_date,KeyType=RANGE ^
     --global-secondary-indexes ^
         IndexName=user_id-index,KeySchema=[{AttributeName=user_id,KeyType=HASH}],Projection={ProjectionType=ALL} ^
     --billing-mode PAY_PER_REQUEST
@@ -124,6 +122,121 @@ aws dynamodb create-table ^
         IndexName=user_id-index,KeySchema=[{AttributeName=user_id,KeyType=HASH}],Projection={ProjectionType=ALL} ^
     --billing-mode PAY_PER_REQUEST
 
+# Table 8: conversation_summaries
+echo "Creating conversation_summaries table..."
+aws dynamodb delete-table --table-name conversation_summaries
+aws dynamodb create-table ^
+    --table-name conversation_summaries ^
+    --attribute-definitions ^
+        AttributeName=conversation_id,AttributeType=S ^
+    --key-schema ^
+        AttributeName=conversation_id,KeyType=HASH ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 9: trip_changes
+echo "Creating trip_changes table..."
+aws dynamodb delete-table --table-name trip_changes
+aws dynamodb create-table ^
+    --table-name trip_changes ^
+    --attribute-definitions ^
+        AttributeName=change_id,AttributeType=S ^
+        AttributeName=created_at,AttributeType=S ^
+    --key-schema ^
+        AttributeName=change_id,KeyType=HASH ^
+        AttributeName=created_at,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 10: itineraries
+echo "Creating itineraries table..."
+aws dynamodb delete-table --table-name itineraries
+aws dynamodb create-table ^
+    --table-name itineraries ^
+    --attribute-definitions ^
+        AttributeName=trip_id,AttributeType=S ^
+        AttributeName=mode,AttributeType=S ^
+    --key-schema ^
+        AttributeName=trip_id,KeyType=HASH ^
+        AttributeName=mode,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 11: idempotency_records
+echo "Creating idempotency_records table..."
+aws dynamodb delete-table --table-name idempotency_records
+aws dynamodb create-table ^
+    --table-name idempotency_records ^
+    --attribute-definitions ^
+        AttributeName=user_id,AttributeType=S ^
+        AttributeName=idempotency_key,AttributeType=S ^
+    --key-schema ^
+        AttributeName=user_id,KeyType=HASH ^
+        AttributeName=idempotency_key,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 12: agent_traces
+echo "Creating agent_traces table..."
+aws dynamodb delete-table --table-name agent_traces
+aws dynamodb create-table ^
+    --table-name agent_traces ^
+    --attribute-definitions ^
+        AttributeName=trip_id,AttributeType=S ^
+        AttributeName=chat_id,AttributeType=S ^
+    --key-schema ^
+        AttributeName=trip_id,KeyType=HASH ^
+        AttributeName=chat_id,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 13: message_feedback
+echo "Creating message_feedback table..."
+aws dynamodb delete-table --table-name message_feedback
+aws dynamodb create-table ^
+    --table-name message_feedback ^
+    --attribute-definitions ^
+        AttributeName=trip_id,AttributeType=S ^
+        AttributeName=chat_id,AttributeType=S ^
+    --key-schema ^
+        AttributeName=trip_id,KeyType=HASH ^
+        AttributeName=chat_id,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 14: agent_usage
+echo "Creating agent_usage table..."
+aws dynamodb delete-table --table-name agent_usage
+aws dynamodb create-table ^
+    --table-name agent_usage ^
+    --attribute-definitions ^
+        AttributeName=user_id,AttributeType=S ^
+        AttributeName=call_id,AttributeType=S ^
+    --key-schema ^
+        AttributeName=user_id,KeyType=HASH ^
+        AttributeName=call_id,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 15: agent_daily_usage
+echo "Creating agent_daily_usage table..."
+aws dynamodb delete-table --table-name agent_daily_usage
+aws dynamodb create-table ^
+    --table-name agent_daily_usage ^
+    --attribute-definitions ^
+        AttributeName=user_id,AttributeType=S ^
+        AttributeName=day,AttributeType=S ^
+    --key-schema ^
+        AttributeName=user_id,KeyType=HASH ^
+        AttributeName=day,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
+# Table 16: airports
+echo "Creating airports table..."
+aws dynamodb delete-table --table-name airports
+aws dynamodb create-table ^
+    --table-name airports ^
+    --attribute-definitions ^
+        AttributeName=airport_code,AttributeType=S ^
+        AttributeName=city,AttributeType=S ^
+    --key-schema ^
+        AttributeName=airport_code,KeyType=HASH ^
+        AttributeName=city,KeyType=RANGE ^
+    --billing-mode PAY_PER_REQUEST
+
 echo ""
 echo "All tables created successfully with On-Demand billing!"
 echo ""
@@ -136,6 +249,15 @@ echo "- flight_bookings (flight reservations)"
 echo "- trip (trip planning and management)"
 echo "- accommodations (hotel and accommodation data)"
 echo "- accommodation_bookings (accommodation reservations)"
+echo "- conversation_summaries (rolling summaries of older chat turns)"
+echo "- trip_changes (requirement changes and pending confirmations)"
+echo "- itineraries (trip planner itineraries)"
+echo "- idempotency_records (stored responses for Idempotency-Key retries)"
+echo "- agent_traces (agent requests and tool call traces of chat turns)"
+echo "- message_feedback (ratings of agent messages)"
+echo "- agent_usage (cost of every agent call)"
+echo "- agent_daily_usage (agent calls per user and day, for the daily quota)"
+echo "- airports (cities and countries airports serve)"
 echo ""
 echo "Benefits of On-Demand billing:"
 echo "- Pay only for actual reads/writes"
@@ -580,6 +580,26 @@ class SyntheticDataGenerator:
         layovers = ["Direct", "1 Stop", "2 Stops", "3+ Stops"]
         return random.choice(layovers)
 
+    def airport_code(self, country: str, city: str) -> str:
+        """Return the code of the airport serving a city."""
+        return self.airports.get(country, {}).get(city, f"{city[:3].upper()}")
+
+    def generate_airports_data(self) -> List[Dict[str, Any]]:
+        """Generate the airport serving every city flights are generated for."""
+        airports = []
+        for country, data in self.countries_cities.items():
+            for state in data["states"]:
+                for city in data["cities"][state]:
+                    airport = {
+                        "airport_code": self.airport_code(country, city),
+                        "city": city,
+                        "country": country,
+                    }
+                    if airport not in airports:
+                        airports.append(airport)
+
+        return airports
+
     def generate_flights_data(self, num_flights: int = 1000) -> List[Dict[str, Any]]:
         """Generate diverse flight data."""
         flights = []
@@ -602,12 +622,8 @@ class SyntheticDataGenerator:
             )
 
             # Get airports
-            origin_airport = self.airports.get(country, {}).get(
-                city, f"{city[:3].upper()}"
-            )
-            dest_airport = self.airports.get(dest_country, {}).get(
-                dest_city, f"{dest_city[:3].upper()}"
-            )
+            origin_airport = self.airport_code(country, city)
+            dest_airport = self.airport_code(dest_country, dest_city)
 
             # Generate flight details
             airline = random.choice(self.airlines)
@@ -886,6 +902,11 @@ def main():
     flights_data = generator.generate_flights_data(num_flights=1000)
     generator.save_to_csv(flights_data, "flights.csv")
 
+    # Generate airports data
+    print("\n🛫 Generating airports data...")
+    airports_data = generator.generate_airports_data()
+    generator.save_to_csv(airports_data, "airports.csv")
+
     # Generate accommodations data
     print("\n🏨 Generating accommodations data...")
     accommodations_data = generator.generate_accommodations_data(num_accommodations=500)